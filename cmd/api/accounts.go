@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type accountKey string
+
+const accountCtx accountKey = "account"
+
+type CreateAccountPayload struct {
+	Name           string `json:"name" validate:"required,max=100"`
+	Type           string `json:"type" validate:"required,oneof=checking savings credit_card cash loan investment"`
+	Currency       string `json:"currency" validate:"required,iso4217"`
+	OpeningBalance int64  `json:"opening_balance"`
+
+	LowBalanceThreshold *int64 `json:"low_balance_threshold"`
+}
+
+type UpdateAccountPayload struct {
+	Name           *string `json:"name" validate:"omitempty,max=100"`
+	Type           *string `json:"type" validate:"omitempty,oneof=checking savings credit_card cash loan investment"`
+	OpeningBalance *int64  `json:"opening_balance"`
+
+	LowBalanceThreshold *int64 `json:"low_balance_threshold"`
+}
+
+func (app *application) createAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Currency = strings.ToUpper(payload.Currency)
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	account := &model.Account{
+		UserID:         user.ID,
+		Name:           payload.Name,
+		Type:           model.AccountType(payload.Type),
+		Currency:       payload.Currency,
+		OpeningBalance: payload.OpeningBalance,
+
+		LowBalanceThreshold: payload.LowBalanceThreshold,
+	}
+
+	if err := app.store.Accounts.Create(r.Context(), account); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, account)
+}
+
+func (app *application) listAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	accounts, err := app.store.Accounts.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+func (app *application) getAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromContext(r)
+
+	writeJSON(w, http.StatusOK, account)
+}
+
+func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromContext(r)
+
+	var payload UpdateAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if payload.Name != nil {
+		account.Name = *payload.Name
+	}
+	if payload.Type != nil {
+		account.Type = model.AccountType(*payload.Type)
+	}
+	if payload.OpeningBalance != nil {
+		account.OpeningBalance = *payload.OpeningBalance
+	}
+	if payload.LowBalanceThreshold != nil {
+		account.LowBalanceThreshold = payload.LowBalanceThreshold
+	}
+
+	if err := app.store.Accounts.Update(r.Context(), account); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, account)
+}
+
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromContext(r)
+
+	if err := app.store.Accounts.Delete(r.Context(), account.UserID, account.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// accountsContextMiddleware loads the account named in the URL and makes sure
+// it belongs to the authenticated user before handing it to the next handler.
+func (app *application) accountsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountID, err := strconv.ParseUint(chi.URLParam(r, "accountID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		account, err := app.store.Accounts.GetByID(ctx, user.ID, uint(accountID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, accountCtx, account)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getAccountFromContext(r *http.Request) *model.Account {
+	account, _ := r.Context().Value(accountCtx).(*model.Account)
+	return account
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/nelsonfrank/finance-tracker/internal/auth"
 	"github.com/nelsonfrank/finance-tracker/internal/mailer"
+	"github.com/nelsonfrank/finance-tracker/internal/notify"
+	"github.com/nelsonfrank/finance-tracker/internal/storage"
 	"github.com/nelsonfrank/finance-tracker/internal/store"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
@@ -21,6 +23,8 @@ type application struct {
 	db            *gorm.DB
 	authenticator auth.Authenticator
 	mailer        mailer.Client
+	notifier      notify.Emitter
+	blobs         storage.Bucket
 	logger        *zap.SugaredLogger
 }
 
@@ -32,6 +36,8 @@ type config struct {
 	oAuth       oAuthConfig
 	mfa         mfaConfig
 	mail        mailConfig
+	scheduler   schedulerConfig
+	attachments attachmentsConfig
 }
 
 type dbConfig struct {
@@ -47,6 +53,11 @@ type oAuthConfig struct {
 
 type mfaConfig struct {
 	token jwtToken
+
+	// issuer is the name authenticator apps list the account under, and
+	// challengeExp how long the second login step may take.
+	issuer       string
+	challengeExp time.Duration
 }
 
 type jwtToken struct {
@@ -61,6 +72,17 @@ type mailConfig struct {
 	mailTrap  mailTrapConfig
 	fromEmail string
 	exp       time.Duration
+	resetExp  time.Duration
+}
+
+type schedulerConfig struct {
+	interval time.Duration
+}
+
+type attachmentsConfig struct {
+	dir     string
+	maxSize int64
+	quota   int64
 }
 
 type mailTrapConfig struct {
@@ -105,7 +127,11 @@ func (app *application) mount() http.Handler {
 
 			// MFA
 			r.Post("/register", app.register)
+			r.Post("/resend-activation", app.resendActivationHandler)
 			r.Post("/login", app.login)
+			r.Post("/mfa", app.verifyMFAHandler)
+			r.Post("/forgot-password", app.forgotPasswordHandler)
+			r.Post("/reset-password", app.resetPasswordHandler)
 			r.Post("/logout", app.logout)
 			r.Group(func(r chi.Router) {
 				r.Use(app.RefreshTokenMiddleware)
@@ -113,10 +139,205 @@ func (app *application) mount() http.Handler {
 			})
 		})
 
+		r.Put("/users/activate/{token}", app.activateUserHandler)
+
+		r.Route("/users/me", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getCurrentUserHandler)
+			r.Put("/settings", app.updateSettingsHandler)
+
+			r.Route("/mfa", func(r chi.Router) {
+				r.Get("/", app.getMFAHandler)
+				r.Post("/totp", app.startTOTPHandler)
+				r.Post("/totp/confirm", app.confirmTOTPHandler)
+				r.Post("/totp/disable", app.disableTOTPHandler)
+				r.Post("/recovery-codes", app.regenerateRecoveryCodesHandler)
+			})
+
+			r.Route("/sessions", func(r chi.Router) {
+				r.Get("/", app.listSessionsHandler)
+				r.Delete("/", app.deleteOtherSessionsHandler)
+				r.Delete("/{sessionID}", app.deleteSessionHandler)
+			})
+		})
+
+		r.Route("/exchange-rates", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listExchangeRatesHandler)
+			r.Put("/", app.upsertExchangeRatesHandler)
+		})
+
 		r.Route("/dashboard", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
 			r.Get("/", app.dashboardHandler)
 		})
+
+		r.Route("/goals", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listGoalsHandler)
+			r.Post("/", app.createGoalHandler)
+
+			r.Route("/{goalID}", func(r chi.Router) {
+				r.Use(app.goalsContextMiddleware)
+				r.Get("/", app.getGoalHandler)
+				r.Put("/", app.updateGoalHandler)
+				r.Delete("/", app.deleteGoalHandler)
+				r.Get("/contributions", app.listGoalContributionsHandler)
+				r.Post("/contributions", app.createGoalContributionHandler)
+			})
+		})
+
+		r.Route("/debts", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listDebtsHandler)
+			r.Post("/", app.createDebtHandler)
+			r.Post("/plan", app.planDebtsHandler)
+
+			r.Route("/{debtID}", func(r chi.Router) {
+				r.Use(app.debtsContextMiddleware)
+				r.Get("/", app.getDebtHandler)
+				r.Put("/", app.updateDebtHandler)
+				r.Delete("/", app.deleteDebtHandler)
+			})
+		})
+
+		r.Route("/forecast", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.forecastHandler)
+		})
+
+		r.Route("/reports", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/cash-flow", app.cashFlowReportHandler)
+			r.Get("/net-worth", app.netWorthReportHandler)
+			r.Post("/net-worth/backfill", app.backfillNetWorthHandler)
+		})
+
+		r.Route("/accounts", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listAccountsHandler)
+			r.Post("/", app.createAccountHandler)
+
+			r.Route("/{accountID}", func(r chi.Router) {
+				r.Use(app.accountsContextMiddleware)
+				r.Get("/", app.getAccountHandler)
+				r.Put("/", app.updateAccountHandler)
+				r.Delete("/", app.deleteAccountHandler)
+			})
+		})
+
+		r.Route("/transactions", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listTransactionsHandler)
+			r.Post("/", app.createTransactionHandler)
+
+			r.Get("/duplicates", app.listDuplicatesHandler)
+			r.Post("/duplicates/merge", app.mergeDuplicatesHandler)
+			r.Post("/duplicates/dismiss", app.dismissDuplicatesHandler)
+
+			r.Route("/{transactionID}", func(r chi.Router) {
+				r.Use(app.transactionsContextMiddleware)
+				r.Get("/", app.getTransactionHandler)
+				r.Put("/", app.updateTransactionHandler)
+				r.Delete("/", app.deleteTransactionHandler)
+				r.Put("/splits", app.setTransactionSplitsHandler)
+
+				r.Route("/attachments", func(r chi.Router) {
+					r.Get("/", app.listAttachmentsHandler)
+					r.Post("/", app.uploadAttachmentHandler)
+
+					r.Route("/{attachmentID}", func(r chi.Router) {
+						r.Use(app.attachmentsContextMiddleware)
+						r.Get("/", app.downloadAttachmentHandler)
+						r.Delete("/", app.deleteAttachmentHandler)
+					})
+				})
+			})
+		})
+
+		r.Route("/categories", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listCategoriesHandler)
+			r.Post("/", app.createCategoryHandler)
+
+			r.Route("/{categoryID}", func(r chi.Router) {
+				r.Use(app.categoriesContextMiddleware)
+				r.Get("/", app.getCategoryHandler)
+				r.Put("/", app.updateCategoryHandler)
+				r.Delete("/", app.deleteCategoryHandler)
+			})
+		})
+
+		r.Route("/budgets", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listBudgetsHandler)
+			r.Post("/", app.createBudgetHandler)
+			r.Get("/status", app.budgetStatusHandler)
+
+			r.Route("/{budgetID}", func(r chi.Router) {
+				r.Use(app.budgetsContextMiddleware)
+				r.Get("/", app.getBudgetHandler)
+				r.Put("/", app.updateBudgetHandler)
+				r.Delete("/", app.deleteBudgetHandler)
+			})
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listTagsHandler)
+			r.Post("/", app.createTagHandler)
+			r.Post("/bulk", app.bulkTagHandler)
+
+			r.Route("/{tagID}", func(r chi.Router) {
+				r.Use(app.tagsContextMiddleware)
+				r.Get("/", app.getTagHandler)
+				r.Put("/", app.updateTagHandler)
+				r.Delete("/", app.deleteTagHandler)
+			})
+		})
+
+		r.Route("/rules", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listRulesHandler)
+			r.Post("/", app.createRuleHandler)
+
+			r.Route("/{ruleID}", func(r chi.Router) {
+				r.Use(app.rulesContextMiddleware)
+				r.Get("/", app.getRuleHandler)
+				r.Put("/", app.updateRuleHandler)
+				r.Delete("/", app.deleteRuleHandler)
+				r.Post("/apply", app.applyRuleHandler)
+			})
+		})
+
+		r.Route("/recurring", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listRecurringHandler)
+			r.Post("/", app.createRecurringHandler)
+			r.Get("/upcoming", app.upcomingRecurringHandler)
+
+			r.Route("/{recurringID}", func(r chi.Router) {
+				r.Use(app.recurringContextMiddleware)
+				r.Get("/", app.getRecurringHandler)
+				r.Put("/", app.updateRecurringHandler)
+				r.Delete("/", app.deleteRecurringHandler)
+				r.Post("/skip", app.skipOccurrenceHandler)
+				r.Post("/post", app.postOccurrenceHandler)
+			})
+		})
+
+		r.Route("/imports", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listImportsHandler)
+			r.Post("/csv", app.importCSVHandler)
+			r.Post("/ofx", app.importOFXHandler)
+			r.Delete("/{batchID}", app.undoImportHandler)
+		})
+
+		r.Route("/transfers", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Post("/", app.createTransferHandler)
+		})
 	})
 	return r
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type attachmentKey string
+
+const attachmentCtx attachmentKey = "attachment"
+
+const (
+	maxAttachmentMemory = 2 << 20 // anything above this is spooled to disk
+	sniffLen            = 512     // bytes http.DetectContentType looks at
+)
+
+// attachmentTypes are the sniffed content types accepted as receipts.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// uploadAttachmentHandler accepts a multipart form with the receipt in
+// "file". The content type is sniffed from the first bytes of the file and
+// the type the client claims is ignored.
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	txn := getTransactionFromContext(r)
+	maxSize := app.config.attachments.maxSize
+
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+maxAttachmentMemory)
+	if err := r.ParseMultipartForm(maxAttachmentMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "file is too large")
+		return
+	}
+	if header.Size == 0 {
+		writeJSONError(w, http.StatusBadRequest, "file is empty")
+		return
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !attachmentTypes[contentType] {
+		writeJSONError(w, http.StatusUnsupportedMediaType, "only images and PDF files can be attached")
+		return
+	}
+
+	// Fail early rather than writing a blob that cannot be kept; Create
+	// checks the quota again while holding a lock.
+	used, err := app.store.Attachments.Usage(r.Context(), txn.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if used+header.Size > app.config.attachments.quota {
+		app.storeErrorResponse(w, r, store.ErrQuotaExceeded)
+		return
+	}
+
+	key, err := newBlobKey(txn.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	size, err := app.blobs.Put(r.Context(), key, io.MultiReader(bytes.NewReader(head), file))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	a := &model.Attachment{
+		UserID:        txn.UserID,
+		TransactionID: txn.ID,
+		Filename:      attachmentFilename(header.Filename),
+		ContentType:   contentType,
+		Size:          size,
+		Key:           key,
+	}
+
+	if err := app.store.Attachments.Create(r.Context(), a, app.config.attachments.quota); err != nil {
+		app.deleteBlob(r.Context(), key)
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (app *application) listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	txn := getTransactionFromContext(r)
+
+	attachments, err := app.store.Attachments.List(r.Context(), txn.UserID, txn.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// downloadAttachmentHandler streams the file back to its owner. It is always
+// served as a download with the sniffed content type, so an uploaded file is
+// never rendered inline by the browser.
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	a := getAttachmentFromContext(r)
+
+	blob, err := app.blobs.Open(r.Context(), a.Key)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, blob); err != nil {
+		app.logger.Warnw("attachment download interrupted", "attachment_id", a.ID, "error", err.Error())
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	a := getAttachmentFromContext(r)
+
+	if err := app.store.Attachments.Delete(r.Context(), a.UserID, a.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.deleteBlob(r.Context(), a.Key)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteBlob removes a blob whose record is gone. A failure only leaves an
+// orphaned file behind, so it is logged rather than reported to the client.
+func (app *application) deleteBlob(ctx context.Context, key string) {
+	if err := app.blobs.Delete(ctx, key); err != nil {
+		app.logger.Errorw("failed to delete attachment blob", "key", key, "error", err.Error())
+	}
+}
+
+// newBlobKey returns a random key for a new blob, grouped by user.
+func newBlobKey(userID uint) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("users/%d/%s", userID, hex.EncodeToString(b)), nil
+}
+
+// attachmentFilename keeps only the base name of an uploaded file, which
+// clients on Windows may send with backslashes.
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "/" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+func (app *application) attachmentsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attachmentID, err := strconv.ParseUint(chi.URLParam(r, "attachmentID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		txn := getTransactionFromContext(r)
+
+		ctx := r.Context()
+
+		a, err := app.store.Attachments.GetByID(ctx, txn.UserID, txn.ID, uint(attachmentID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, attachmentCtx, a)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getAttachmentFromContext(r *http.Request) *model.Attachment {
+	a, _ := r.Context().Value(attachmentCtx).(*model.Attachment)
+	return a
+}
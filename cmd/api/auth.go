@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/go-chi/chi/v5"
 	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/mailer"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
 	"gorm.io/gorm"
@@ -17,6 +23,13 @@ import (
 
 var randomState string = "random"
 
+// activationResendInterval and passwordResetInterval are how long a user has
+// to wait between two activation or password reset emails.
+const (
+	activationResendInterval = time.Minute
+	passwordResetInterval    = time.Minute
+)
+
 // ValidationError represents a custom error response
 type ValidationError struct {
 	Field string `json:"field"`
@@ -40,11 +53,25 @@ type LoginResponse struct {
 	User         model.User `json:"user"`
 }
 
+type ResendActivationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
 type RefreshTokenPayload struct {
 	RefreshToken string `json:"refresh_token"`
 }
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (app *application) register(w http.ResponseWriter, r *http.Request) {
@@ -84,15 +111,99 @@ func (app *application) register(w http.ResponseWriter, r *http.Request) {
 		LastName:  payload.LastName,
 	}
 
-	if result := app.db.Create(&user); result.Error != nil {
+	plainToken, tokenHash, err := newToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.CreateAndInvite(r.Context(), &user, tokenHash, app.config.mail.exp); err != nil {
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 
+	if err := app.sendInvitation(&user, plainToken); err != nil {
+		// Without the email the account could never be activated, so
+		// undo the registration and let the user try again.
+		if err := app.store.Users.Delete(r.Context(), user.ID); err != nil {
+			app.logger.Errorw("error deleting user", "user_id", user.ID, "error", err.Error())
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	writeJSON(w, http.StatusCreated, user)
 
 }
 
+// activateUserHandler activates the account holding the token from an
+// invitation email. Each token works once.
+func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	if err := app.store.Users.Activate(r.Context(), hashToken(token), time.Now()); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resendActivationHandler sends a fresh invitation, which replaces the
+// previous one, to a user who has not activated their account yet. Unknown,
+// already active and throttled addresses all get the same response, and
+// mail errors are only logged, so no address can be told apart.
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendActivationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	var user model.User
+	result := app.db.Where("email = ?", payload.Email).First(&user)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		app.internalServerError(w, r, result.Error)
+		return
+	}
+	if result.Error != nil || user.IsActive {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	last, err := app.store.Users.LastInvitedAt(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if time.Since(last) < activationResendInterval {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	plainToken, tokenHash, err := newToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.Invite(r.Context(), user.ID, tokenHash, app.config.mail.exp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.sendInvitation(&user, plainToken); err != nil {
+		app.logger.Errorw("error sending activation email", "user_id", user.ID, "error", err.Error())
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func (app *application) login(w http.ResponseWriter, r *http.Request) {
 	var payload LoginUserPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -124,69 +235,250 @@ func (app *application) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT Access token
-	claims := app.authenticator.JwtClaimGenerator(
-		user.ID,
-		app.config.mfa.token.exp,
-		app.config.mfa.token.iss,
-		app.config.mfa.token.iss,
-	)
+	if !user.IsActive {
+		writeJSONError(w, http.StatusForbidden, "Account is not activated")
+		return
+	}
+
+	// Users with two-factor authentication finish logging in at /auth/mfa
+	if user.TOTPEnabled {
+		app.mfaChallengeResponse(w, r, user)
+		return
+	}
 
-	accessToken, err := app.authenticator.GenerateToken(claims)
+	app.startSession(w, r, user)
+}
 
+// startSession signs the user in on the device making the request and
+// responds with the access and refresh tokens.
+func (app *application) startSession(w http.ResponseWriter, r *http.Request, user model.User) {
+	refreshToken, record, err := app.newRefreshToken(r)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, "Error generating token")
+		app.internalServerError(w, r, err)
 		return
 	}
 
-	// Generate JWT Refresh token
-	refreshTokenClaims := app.authenticator.JwtClaimGenerator(
-		user.ID,
-		app.config.mfa.token.refreshTokenExp,
-		app.config.mfa.token.iss,
-		app.config.mfa.token.iss)
-
-	refreshToken, err := app.authenticator.GenerateToken(refreshTokenClaims)
+	record.UserID = user.ID
+	if err := app.store.RefreshTokens.Create(r.Context(), record); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
+	accessToken, err := app.newAccessToken(user.ID, record.SessionID)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "Error generating token")
 		return
 	}
 
+	app.setRefreshTokenCookie(w, refreshToken)
+
 	writeJSON(w, http.StatusOK, &LoginResponse{
 		accessToken,
 		refreshToken,
 		user,
 	})
+}
+
+// forgotPasswordHandler emails a password reset link to the address, if it
+// belongs to a user. The response is the same whether or not it does, and
+// whether or not an email was sent, so accounts cannot be enumerated.
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	var user model.User
+	result := app.db.Where("email = ?", payload.Email).First(&user)
+	if result.Error != nil {
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			app.internalServerError(w, r, result.Error)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	last, err := app.store.Users.LastPasswordResetAt(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if time.Since(last) < passwordResetInterval {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	plainToken, tokenHash, err := newToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.RequestPasswordReset(r.Context(), user.ID, tokenHash, app.config.mail.resetExp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.FirstName,
+		ResetURL:  fmt.Sprintf("%s/auth/reset-password?token=%s", app.config.frontendURL, plainToken),
+		ExpiresIn: fmt.Sprintf("%d minutes", int(app.config.mail.resetExp.Minutes())),
+	}
+
+	isProdEnv := app.config.env == "production"
+
+	// A failure is only logged; reporting it would reveal the account exists.
+	status, err := app.mailer.Send(mailer.ResetPasswordTemplate, user.FirstName, user.Email, vars, !isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending password reset email", "user_id", user.ID, "error", err.Error())
+	} else {
+		app.logger.Infow("password reset email sent", "user_id", user.ID, "status", status)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
 }
 
+// resetPasswordHandler sets a new password using the token from a reset
+// email. The user is signed out everywhere by revoking their refresh tokens.
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	err = app.store.Users.ResetPassword(r.Context(), hashToken(payload.Token), string(hashedPassword), time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSONError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendInvitation emails the user the link that activates their account.
+func (app *application) sendInvitation(user *model.User, token string) error {
+	vars := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.FirstName,
+		ActivationURL: fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, token),
+	}
+
+	isProdEnv := app.config.env == "production"
+
+	status, err := app.mailer.Send(mailer.UserWelcomeTemplate, user.FirstName, user.Email, vars, !isProdEnv)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Infow("activation email sent", "user_id", user.ID, "status", status)
+
+	return nil
+}
+
+// newToken returns a random token to give to the user together with the
+// SHA-256 hash of it, which is what gets stored.
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// logout revokes the refresh token in the cookie, signing this device out,
+// and clears the cookie. It succeeds even without a token.
 func (app *application) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(refreshTokenCookie); err == nil {
+		if err := app.store.RefreshTokens.Revoke(r.Context(), hashToken(cookie.Value), time.Now()); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.clearRefreshTokenCookie(w)
 
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// refreshTokenHandler rotates the refresh token: the one presented is used up
+// and a new one is returned together with a new access token. Presenting a
+// token that was already used signs its session out.
 func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	refreshToken, record, err := app.newRefreshToken(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
-	user := getUserFromContext(r)
+	err = app.store.RefreshTokens.Rotate(r.Context(), hashToken(getRefreshTokenFromContext(r)), record, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTokenReused):
+			app.logger.Warnw("refresh token reused, session revoked", "user_id", record.UserID, "session_id", record.SessionID, "ip", record.IP)
+			app.clearRefreshTokenCookie(w)
+			app.unauthorizedErrorResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.clearRefreshTokenCookie(w)
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
 
-	// Generate JWT Access token
-	newClaims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(app.config.mfa.token.exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": app.config.mfa.token.iss,
-		"aud": app.config.mfa.token.iss,
+	user, err := app.getUser(r.Context(), int64(record.UserID))
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
 	}
-	accessToken, err := app.authenticator.GenerateToken(newClaims)
 
+	accessToken, err := app.newAccessToken(user.ID, record.SessionID)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "Error generating token")
 		return
 	}
 
+	app.setRefreshTokenCookie(w, refreshToken)
+
 	writeJSON(w, http.StatusOK, &RefreshTokenResponse{
 		accessToken,
+		refreshToken,
 	})
 }
 
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type budgetKey string
+
+const budgetCtx budgetKey = "budget"
+
+const monthLayout = "2006-01"
+
+type CreateBudgetPayload struct {
+	CategoryID uint   `json:"category_id" validate:"required"`
+	Month      string `json:"month" validate:"required,datetime=2006-01"`
+	Amount     int64  `json:"amount" validate:"gte=0"`
+	Rollover   bool   `json:"rollover"`
+}
+
+type UpdateBudgetPayload struct {
+	Amount   *int64 `json:"amount" validate:"omitempty,gte=0"`
+	Rollover *bool  `json:"rollover"`
+}
+
+func (app *application) createBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateBudgetPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	month, _ := time.Parse(monthLayout, payload.Month)
+	user := getUserFromContext(r)
+
+	b := &model.Budget{
+		UserID:     user.ID,
+		CategoryID: payload.CategoryID,
+		Month:      month,
+		Amount:     payload.Amount,
+		Rollover:   payload.Rollover,
+	}
+
+	if err := app.store.Budgets.Create(r.Context(), b); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// listBudgetsHandler returns all budgets, or those of a single month when the
+// month query parameter (YYYY-MM) is given.
+func (app *application) listBudgetsHandler(w http.ResponseWriter, r *http.Request) {
+	var month *time.Time
+	if m := r.URL.Query().Get("month"); m != "" {
+		parsed, err := time.Parse(monthLayout, m)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		month = &parsed
+	}
+
+	user := getUserFromContext(r)
+
+	budgets, err := app.store.Budgets.List(r.Context(), user.ID, month)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, budgets)
+}
+
+// budgetStatusHandler reports budgeted, spent and remaining amounts for every
+// budget in the requested month, defaulting to the current one.
+func (app *application) budgetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	month := time.Now()
+	if m := r.URL.Query().Get("month"); m != "" {
+		parsed, err := time.Parse(monthLayout, m)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		month = parsed
+	}
+
+	user := getUserFromContext(r)
+
+	statuses, err := app.store.Budgets.Statuses(r.Context(), user.ID, month)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (app *application) getBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	b := getBudgetFromContext(r)
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (app *application) updateBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	b := getBudgetFromContext(r)
+
+	var payload UpdateBudgetPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if payload.Amount != nil {
+		b.Amount = *payload.Amount
+	}
+	if payload.Rollover != nil {
+		b.Rollover = *payload.Rollover
+	}
+
+	if err := app.store.Budgets.Update(r.Context(), b); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (app *application) deleteBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	b := getBudgetFromContext(r)
+
+	if err := app.store.Budgets.Delete(r.Context(), b.UserID, b.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) budgetsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		budgetID, err := strconv.ParseUint(chi.URLParam(r, "budgetID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		b, err := app.store.Budgets.GetByID(ctx, user.ID, uint(budgetID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, budgetCtx, b)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getBudgetFromContext(r *http.Request) *model.Budget {
+	b, _ := r.Context().Value(budgetCtx).(*model.Budget)
+	return b
+}
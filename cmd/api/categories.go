@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type categoryKey string
+
+const categoryCtx categoryKey = "category"
+
+type CreateCategoryPayload struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Kind     string `json:"kind" validate:"required,oneof=income expense"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// UpdateCategoryPayload leaves out fields that do not change. A parent_id of
+// 0 moves the category back to the root.
+type UpdateCategoryPayload struct {
+	Name     *string `json:"name" validate:"omitempty,max=100"`
+	ParentID *uint   `json:"parent_id"`
+}
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateCategoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	category := &model.Category{
+		UserID:   user.ID,
+		ParentID: payload.ParentID,
+		Name:     payload.Name,
+		Kind:     model.CategoryKind(payload.Kind),
+	}
+
+	if err := app.store.Categories.Create(r.Context(), category); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, category)
+}
+
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	categories, err := app.store.Categories.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, categories)
+}
+
+func (app *application) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category := getCategoryFromContext(r)
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category := getCategoryFromContext(r)
+
+	var payload UpdateCategoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if payload.Name != nil {
+		category.Name = *payload.Name
+	}
+	if payload.ParentID != nil {
+		category.ParentID = payload.ParentID
+	}
+
+	if err := app.store.Categories.Update(r.Context(), category); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// deleteCategoryHandler requires a replacement_id query parameter naming the
+// category that takes over the deleted category's transactions.
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category := getCategoryFromContext(r)
+
+	replacementID, err := strconv.ParseUint(r.URL.Query().Get("replacement_id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "replacement_id is required")
+		return
+	}
+
+	if err := app.store.Categories.Delete(r.Context(), category.UserID, category.ID, uint(replacementID)); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) categoriesContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		categoryID, err := strconv.ParseUint(chi.URLParam(r, "categoryID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		category, err := app.store.Categories.GetByID(ctx, user.ID, uint(categoryID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, categoryCtx, category)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCategoryFromContext(r *http.Request) *model.Category {
+	category, _ := r.Context().Value(categoryCtx).(*model.Category)
+	return category
+}
@@ -2,8 +2,32 @@ package main
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 )
 
+const defaultRecentTransactions = 10
+
+// dashboardHandler returns the summary for the authenticated user. The number
+// of recent transactions can be changed with the recent query parameter.
 func (app *application) dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, "hello there")
+	recent := defaultRecentTransactions
+	if v := r.URL.Query().Get("recent"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			writeJSONError(w, http.StatusBadRequest, "recent must be between 1 and 100")
+			return
+		}
+		recent = n
+	}
+
+	user := getUserFromContext(r)
+
+	summary, err := app.store.Dashboard.Summary(r.Context(), user.ID, time.Now(), recent)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
 }
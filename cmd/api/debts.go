@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/payoff"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type debtKey string
+
+const debtCtx debtKey = "debt"
+
+type CreateDebtPayload struct {
+	AccountID      uint  `json:"account_id" validate:"required"`
+	APR            int   `json:"apr" validate:"gte=0,max=100000"`
+	MinimumPayment int64 `json:"minimum_payment" validate:"gt=0"`
+}
+
+type UpdateDebtPayload struct {
+	APR            *int   `json:"apr" validate:"omitempty,gte=0,max=100000"`
+	MinimumPayment *int64 `json:"minimum_payment" validate:"omitempty,gt=0"`
+}
+
+// PlanDebtsPayload asks for a payoff simulation. Currency defaults to the
+// user's home currency and Order is only used by the custom strategy.
+type PlanDebtsPayload struct {
+	Strategy     string `json:"strategy" validate:"required,oneof=avalanche snowball custom"`
+	ExtraPayment int64  `json:"extra_payment" validate:"gte=0"`
+	Order        []uint `json:"order" validate:"max=100,unique"`
+	Currency     string `json:"currency" validate:"omitempty,iso4217"`
+}
+
+func (app *application) createDebtHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateDebtPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	d := &model.Debt{
+		UserID:         user.ID,
+		AccountID:      payload.AccountID,
+		APR:            payload.APR,
+		MinimumPayment: payload.MinimumPayment,
+	}
+
+	if err := app.store.Debts.Create(r.Context(), d); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, d)
+}
+
+func (app *application) listDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	debts, err := app.store.Debts.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, debts)
+}
+
+func (app *application) getDebtHandler(w http.ResponseWriter, r *http.Request) {
+	d := getDebtFromContext(r)
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (app *application) updateDebtHandler(w http.ResponseWriter, r *http.Request) {
+	d := getDebtFromContext(r)
+
+	var payload UpdateDebtPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if payload.APR != nil {
+		d.APR = *payload.APR
+	}
+	if payload.MinimumPayment != nil {
+		d.MinimumPayment = *payload.MinimumPayment
+	}
+
+	if err := app.store.Debts.Update(r.Context(), d); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (app *application) deleteDebtHandler(w http.ResponseWriter, r *http.Request) {
+	d := getDebtFromContext(r)
+
+	if err := app.store.Debts.Delete(r.Context(), d.UserID, d.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// planDebtsHandler simulates paying off the user's debts with the chosen
+// strategy and extra monthly payment, and returns the month-by-month
+// schedule with total interest and payoff date per debt.
+func (app *application) planDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PlanDebtsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Currency = strings.ToUpper(payload.Currency)
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	q := store.PlanQuery{
+		Currency: payload.Currency,
+		Strategy: payoff.Strategy(payload.Strategy),
+		Extra:    payload.ExtraPayment,
+		Order:    payload.Order,
+	}
+	if q.Currency == "" {
+		q.Currency = user.HomeCurrency
+	}
+
+	plan, err := app.store.Debts.Plan(r.Context(), user.ID, q, today())
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (app *application) debtsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		debtID, err := strconv.ParseUint(chi.URLParam(r, "debtID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		d, err := app.store.Debts.GetByID(ctx, user.ID, uint(debtID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, debtCtx, d)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getDebtFromContext(r *http.Request) *model.Debt {
+	d, _ := r.Context().Value(debtCtx).(*model.Debt)
+	return d
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nelsonfrank/finance-tracker/internal/dedupe"
+)
+
+type MergeDuplicatesPayload struct {
+	KeepID   uint `json:"keep_id" validate:"required"`
+	RemoveID uint `json:"remove_id" validate:"required,nefield=KeepID"`
+}
+
+type DismissDuplicatesPayload struct {
+	TransactionIDs []uint `json:"transaction_ids" validate:"required,len=2,unique"`
+}
+
+// listDuplicatesHandler returns suspected duplicate pairs. The date window in
+// days and the minimum score can be tuned with the window and min_score query
+// parameters.
+func (app *application) listDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	window := dedupe.DefaultWindowDays
+	if v := r.URL.Query().Get("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 30 {
+			writeJSONError(w, http.StatusBadRequest, "window must be between 0 and 30 days")
+			return
+		}
+		window = n
+	}
+
+	minScore := dedupe.DefaultMinScore
+	if v := r.URL.Query().Get("min_score"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			writeJSONError(w, http.StatusBadRequest, "min_score must be between 0 and 1")
+			return
+		}
+		minScore = f
+	}
+
+	user := getUserFromContext(r)
+
+	pairs, err := app.store.Duplicates.Find(r.Context(), user.ID, window, minScore)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pairs)
+}
+
+func (app *application) mergeDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MergeDuplicatesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	txn, err := app.store.Duplicates.Merge(r.Context(), user.ID, payload.KeepID, payload.RemoveID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, txn)
+}
+
+func (app *application) dismissDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload DismissDuplicatesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	err := app.store.Duplicates.Dismiss(r.Context(), user.ID, payload.TransactionIDs[0], payload.TransactionIDs[1])
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
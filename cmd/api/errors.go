@@ -1,7 +1,13 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/nelsonfrank/finance-tracker/internal/payoff"
+	"github.com/nelsonfrank/finance-tracker/internal/rules"
+	"github.com/nelsonfrank/finance-tracker/internal/schedule"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
 )
 
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
@@ -55,3 +61,31 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 
 	writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
+
+// storeErrorResponse maps the sentinel errors returned by the store to the
+// matching HTTP response.
+func (app *application) storeErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	case errors.Is(err, store.ErrConflict):
+		app.conflictResponse(w, r, err)
+	case errors.Is(err, store.ErrInvalidCursor),
+		errors.Is(err, store.ErrInvalidTransfer),
+		errors.Is(err, store.ErrInvalidCategory),
+		errors.Is(err, store.ErrInvalidSplit),
+		errors.Is(err, store.ErrInvalidMerge),
+		errors.Is(err, rules.ErrInvalidRule),
+		errors.Is(err, schedule.ErrInvalidRule),
+		errors.Is(err, store.ErrInvalidOccurrence),
+		errors.Is(err, store.ErrInvalidGoal),
+		errors.Is(err, store.ErrInvalidDebt),
+		errors.Is(err, payoff.ErrInvalidPlan),
+		errors.Is(err, payoff.ErrNoPayoff):
+		app.badRequestResponse(w, r, err)
+	case errors.Is(err, store.ErrQuotaExceeded):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
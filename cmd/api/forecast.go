@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+)
+
+// forecastHorizons are the accepted values of the days query parameter.
+var forecastHorizons = map[string]int{"30": 30, "90": 90, "365": 365}
+
+// forecastHandler projects each account's balance day by day for the next
+// 30, 90 (the default) or 365 days, chosen with the days query parameter.
+func (app *application) forecastHandler(w http.ResponseWriter, r *http.Request) {
+	days := 90
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, ok := forecastHorizons[v]
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "days must be one of 30, 90 or 365")
+			return
+		}
+		days = n
+	}
+
+	user := getUserFromContext(r)
+
+	f, err := app.store.Forecast.Project(r.Context(), user.ID, today(), days)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, f)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type goalKey string
+
+const goalCtx goalKey = "goal"
+
+// GoalPayload is used both to create a goal and to replace one.
+type GoalPayload struct {
+	Name         string  `json:"name" validate:"required,max=100"`
+	AccountID    uint    `json:"account_id" validate:"required"`
+	TargetAmount int64   `json:"target_amount" validate:"gt=0"`
+	TargetDate   *string `json:"target_date" validate:"omitempty,datetime=2006-01-02"`
+	Earmarked    bool    `json:"earmarked"`
+}
+
+type CreateGoalContributionPayload struct {
+	Amount int64  `json:"amount" validate:"required"`
+	Date   string `json:"date" validate:"omitempty,datetime=2006-01-02"`
+	Memo   string `json:"memo" validate:"max=255"`
+}
+
+func (p GoalPayload) toGoal(g *model.Goal) {
+	g.Name = p.Name
+	g.AccountID = p.AccountID
+	g.TargetAmount = p.TargetAmount
+	g.TargetDate = nil
+	if p.TargetDate != nil {
+		date, _ := time.Parse(dateLayout, *p.TargetDate)
+		g.TargetDate = &date
+	}
+	g.Earmarked = p.Earmarked
+}
+
+func (app *application) createGoalHandler(w http.ResponseWriter, r *http.Request) {
+	var payload GoalPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	g := &model.Goal{UserID: user.ID}
+	payload.toGoal(g)
+
+	if err := app.store.Goals.Create(r.Context(), g); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeGoalProgress(w, r, http.StatusCreated, g)
+}
+
+// listGoalsHandler returns every goal with its progress, required monthly
+// contribution and projected completion date.
+func (app *application) listGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	goals, err := app.store.Goals.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	progress, err := app.store.Goals.Progress(r.Context(), goals, today())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, progress)
+}
+
+func (app *application) getGoalHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeGoalProgress(w, r, http.StatusOK, getGoalFromContext(r))
+}
+
+func (app *application) updateGoalHandler(w http.ResponseWriter, r *http.Request) {
+	g := getGoalFromContext(r)
+
+	var payload GoalPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	payload.toGoal(g)
+
+	if err := app.store.Goals.Update(r.Context(), g); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeGoalProgress(w, r, http.StatusOK, g)
+}
+
+func (app *application) deleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+	g := getGoalFromContext(r)
+
+	if err := app.store.Goals.Delete(r.Context(), g.UserID, g.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createGoalContributionHandler puts money aside for an earmarked goal, or
+// takes it back with a negative amount. The date defaults to today.
+func (app *application) createGoalContributionHandler(w http.ResponseWriter, r *http.Request) {
+	g := getGoalFromContext(r)
+
+	var payload CreateGoalContributionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	date := today()
+	if payload.Date != "" {
+		date, _ = time.Parse(dateLayout, payload.Date)
+	}
+
+	c := &model.GoalContribution{
+		UserID: g.UserID,
+		GoalID: g.ID,
+		Amount: payload.Amount,
+		Date:   date,
+		Memo:   payload.Memo,
+	}
+
+	if err := app.store.Goals.Contribute(r.Context(), c); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (app *application) listGoalContributionsHandler(w http.ResponseWriter, r *http.Request) {
+	g := getGoalFromContext(r)
+
+	contributions, err := app.store.Goals.Contributions(r.Context(), g.UserID, g.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contributions)
+}
+
+func (app *application) writeGoalProgress(w http.ResponseWriter, r *http.Request, status int, g *model.Goal) {
+	progress, err := app.store.Goals.Progress(r.Context(), []model.Goal{*g}, today())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, status, progress[0])
+}
+
+func (app *application) goalsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		goalID, err := strconv.ParseUint(chi.URLParam(r, "goalID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		g, err := app.store.Goals.GetByID(ctx, user.ID, uint(goalID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, goalCtx, g)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getGoalFromContext(r *http.Request) *model.Goal {
+	g, _ := r.Context().Value(goalCtx).(*model.Goal)
+	return g
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/importer"
+	"github.com/nelsonfrank/finance-tracker/internal/money"
+	"github.com/nelsonfrank/finance-tracker/internal/ofx"
+)
+
+const (
+	maxImportBytes  = 10 << 20 // 10mb
+	maxImportMemory = 2 << 20  // anything above this is spooled to disk
+)
+
+type ImportPreview struct {
+	Total      int                `json:"total"`
+	Valid      int                `json:"valid"`
+	Invalid    int                `json:"invalid"`
+	Duplicates int                `json:"duplicates"`
+	Rows       []importer.Row     `json:"rows"`
+	Batch      *model.ImportBatch `json:"batch,omitempty"`
+}
+
+// importCSVHandler accepts a multipart form with the statement in "file", the
+// column mapping as JSON in "mapping" and the target "account_id". Without
+// commit=true it only returns the parsed rows so the client can review them.
+// Committing fails if any row has errors unless skip_invalid=true is passed,
+// in which case only the valid rows are imported.
+func (app *application) importCSVHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	file, header, account, ok := app.readImportForm(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	var mapping importer.Mapping
+	if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid mapping: %w", err))
+		return
+	}
+
+	if err := Validate.Struct(mapping); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	rows, err := importer.ParseCSV(file, mapping, money.Exponent(account.Currency))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.previewOrCommitImport(w, r, &model.ImportBatch{
+		UserID:    user.ID,
+		AccountID: account.ID,
+		Source:    model.ImportSourceCSV,
+		Filename:  header.Filename,
+	}, rows)
+}
+
+// importOFXHandler imports an OFX or QFX statement uploaded in "file" into
+// "account_id". When the file holds several statements, "ofx_account_id"
+// selects the one to import by the bank's account number. Rows whose FITID is
+// already on the account are reported as duplicates and never imported twice.
+func (app *application) importOFXHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	file, header, account, ok := app.readImportForm(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	statements, err := ofx.Parse(file)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	statement := statements[0]
+	if len(statements) > 1 {
+		want := r.FormValue("ofx_account_id")
+		found := false
+		for _, st := range statements {
+			if want != "" && st.AccountID == want {
+				statement, found = st, true
+				break
+			}
+		}
+		if !found {
+			writeJSONError(w, http.StatusBadRequest, "the file contains several statements, choose one with ofx_account_id")
+			return
+		}
+	}
+
+	if statement.Currency != "" && statement.Currency != account.Currency {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("statement currency %s does not match account currency %s", statement.Currency, account.Currency))
+		return
+	}
+
+	rows := importer.FromOFX(statement, money.Exponent(account.Currency))
+
+	fitids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		fitids = append(fitids, row.FITID)
+	}
+
+	existing, err := app.store.Imports.ExistingFITIDs(r.Context(), user.ID, account.ID, fitids)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+	for i := range rows {
+		if existing[rows[i].FITID] {
+			rows[i].Duplicate = true
+		}
+	}
+
+	app.previewOrCommitImport(w, r, &model.ImportBatch{
+		UserID:    user.ID,
+		AccountID: account.ID,
+		Source:    model.ImportSourceOFX,
+		Filename:  header.Filename,
+	}, rows)
+}
+
+func (app *application) listImportsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	batches, err := app.store.Imports.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, batches)
+}
+
+// undoImportHandler deletes every transaction created by an import batch
+// together with its attachments.
+func (app *application) undoImportHandler(w http.ResponseWriter, r *http.Request) {
+	batchID, err := strconv.ParseUint(chi.URLParam(r, "batchID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	keys, err := app.store.Imports.Undo(r.Context(), user.ID, uint(batchID))
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	for _, key := range keys {
+		app.deleteBlob(r.Context(), key)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readImportForm parses the multipart upload shared by all statement imports
+// and resolves the target account. It writes the error response itself and
+// returns ok=false when the request cannot be processed.
+func (app *application) readImportForm(w http.ResponseWriter, r *http.Request) (multipart.File, *multipart.FileHeader, *model.Account, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(maxImportMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "file is too large")
+			return nil, nil, nil, false
+		}
+		app.badRequestResponse(w, r, err)
+		return nil, nil, nil, false
+	}
+
+	accountID, err := strconv.ParseUint(r.FormValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "account_id is required")
+		return nil, nil, nil, false
+	}
+
+	user := getUserFromContext(r)
+
+	account, err := app.store.Accounts.GetByID(r.Context(), user.ID, uint(accountID))
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return nil, nil, nil, false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "file is required")
+		return nil, nil, nil, false
+	}
+
+	return file, header, account, true
+}
+
+func (app *application) previewOrCommitImport(w http.ResponseWriter, r *http.Request, batch *model.ImportBatch, rows []importer.Row) {
+	preview := &ImportPreview{Total: len(rows), Rows: rows}
+
+	txns := make([]model.Transaction, 0, len(rows))
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			preview.Invalid++
+			continue
+		}
+		if row.Duplicate {
+			preview.Duplicates++
+			continue
+		}
+
+		preview.Valid++
+		txns = append(txns, model.Transaction{
+			Amount: row.Amount,
+			Date:   row.Date,
+			Payee:  row.Payee,
+			Memo:   row.Memo,
+			FITID:  row.FITID,
+		})
+	}
+
+	commit, _ := strconv.ParseBool(r.FormValue("commit"))
+	if !commit {
+		writeJSON(w, http.StatusOK, preview)
+		return
+	}
+
+	skipInvalid, _ := strconv.ParseBool(r.FormValue("skip_invalid"))
+	if preview.Invalid > 0 && !skipInvalid {
+		writeJSON(w, http.StatusUnprocessableEntity, preview)
+		return
+	}
+
+	if err := app.store.Imports.Commit(r.Context(), batch, txns); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	preview.Batch = batch
+	writeJSON(w, http.StatusCreated, preview)
+}
@@ -37,6 +37,24 @@ func GetValidationErrorMsg(err validator.FieldError) string {
 		return "Invalid URL format"
 	case "e164":
 		return "Invalid phone number format"
+	case "oneof":
+		return "Must be one of: " + err.Param()
+	case "len":
+		return "Must have exactly " + err.Param() + " items"
+	case "unique":
+		return "Must not contain duplicates"
+	case "gte":
+		return "Must be at least " + err.Param()
+	case "gt":
+		return "Must be greater than " + err.Param()
+	case "gtefield":
+		return "Must not be before " + strings.ToLower(err.Param())
+	case "nefield":
+		return "Must be different from " + strings.ToLower(err.Param())
+	case "datetime":
+		return "Must be a date in the format " + err.Param()
+	case "iso4217":
+		return "Invalid currency code"
 	case "containsany":
 		return "Must contain at least one special character (!@#$%^&*)"
 	default:
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"time"
 
 	"github.com/nelsonfrank/finance-tracker/internal/auth"
 	"github.com/nelsonfrank/finance-tracker/internal/db"
 	"github.com/nelsonfrank/finance-tracker/internal/env"
 	"github.com/nelsonfrank/finance-tracker/internal/mailer"
+	"github.com/nelsonfrank/finance-tracker/internal/notify"
+	"github.com/nelsonfrank/finance-tracker/internal/storage"
 	"github.com/nelsonfrank/finance-tracker/internal/store"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
@@ -40,9 +43,12 @@ func main() {
 				refreshTokenExp: time.Hour * 24 * 3,
 				exp:             time.Second * 5,
 				iss:             "financial-tracker"},
+			issuer:       env.GetString("TOTP_ISSUER", "Finance Tracker"),
+			challengeExp: time.Minute * 5,
 		},
 		mail: mailConfig{
 			exp:       time.Hour * 24 * 3, // 3 days
+			resetExp:  time.Hour,
 			fromEmail: env.GetString("FROM_EMAIL", ""),
 			sendGrid: sendGridConfig{
 				apiKey: env.GetString("SENDGRID_API_KEY", ""),
@@ -51,6 +57,14 @@ func main() {
 				apiKey: env.GetString("MAILTRAP_API_KEY", ""),
 			},
 		},
+		scheduler: schedulerConfig{
+			interval: time.Minute * 5,
+		},
+		attachments: attachmentsConfig{
+			dir:     env.GetString("ATTACHMENTS_DIR", "./uploads"),
+			maxSize: 10 << 20, // 10mb
+			quota:   int64(env.GetInt("ATTACHMENTS_QUOTA_MB", 100)) << 20,
+		},
 	}
 
 	// Logger
@@ -79,15 +93,26 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	// Attachments
+	blobs, err := storage.NewLocal(cfg.attachments.dir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	app := &application{
 		config:        cfg,
 		store:         store,
 		db:            db,
 		authenticator: jwtAuthenticator,
 		mailer:        mailtrap,
+		notifier:      notify.NewLogEmitter(logger),
+		blobs:         blobs,
 		logger:        logger,
 	}
 
+	// Recurring transactions, snapshots and goal checks
+	go app.runScheduler(context.Background())
+
 	mux := app.mount()
 
 	logger.Fatal((app.run(mux)))
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/auth"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+const (
+	// totpSkew is how many 30 second steps a code may be off by, to allow
+	// for clock drift and the time it takes to type it.
+	totpSkew = 1
+
+	// maxMFAAttempts is how many codes can be tried against one login
+	// challenge before the password has to be given again.
+	maxMFAAttempts = 5
+
+	recoveryCodeCount = 10
+)
+
+// SecondFactorPayload is a code from the user's authenticator app or one of
+// their recovery codes.
+type SecondFactorPayload struct {
+	Code         string `json:"code" validate:"omitempty,len=6"`
+	RecoveryCode string `json:"recovery_code" validate:"omitempty,max=30"`
+}
+
+// MFAVerifyPayload is the second login step for users with two-factor
+// authentication.
+type MFAVerifyPayload struct {
+	Token string `json:"mfa_token" validate:"required,max=100"`
+	SecondFactorPayload
+}
+
+type ConfirmTOTPPayload struct {
+	Code string `json:"code" validate:"required,len=6"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type MFAStatusResponse struct {
+	TOTPEnabled       bool  `json:"totp_enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+type TOTPEnrolmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaChallengeResponse answers a correct password from a user with
+// two-factor authentication with a short-lived challenge token instead of
+// signing them in.
+func (app *application) mfaChallengeResponse(w http.ResponseWriter, r *http.Request, user model.User) {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	challenge := &model.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(app.config.mfa.challengeExp),
+		CreatedAt: now,
+	}
+
+	if err := app.store.MFA.CreateChallenge(r.Context(), challenge); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   challenge.ExpiresAt,
+	})
+}
+
+// verifyMFAHandler exchanges a login challenge and a second factor for the
+// access and refresh tokens.
+func (app *application) verifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFAVerifyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	ctx := r.Context()
+
+	challenge, err := app.store.MFA.AttemptChallenge(ctx, hashToken(payload.Token), maxMFAAttempts, time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user, err := app.getUser(ctx, int64(challenge.UserID))
+	if err != nil || !user.TOTPEnabled {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	ok, err := app.checkSecondFactor(ctx, user, payload.SecondFactorPayload)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	if err := app.store.MFA.DeleteChallenge(ctx, challenge.ID, time.Now()); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.startSession(w, r, user)
+}
+
+func (app *application) getMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	left, err := app.store.MFA.RecoveryCodesLeft(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &MFAStatusResponse{
+		TOTPEnabled:       user.TOTPEnabled,
+		RecoveryCodesLeft: left,
+	})
+}
+
+// startTOTPHandler generates a new secret for the user's authenticator app.
+// Two-factor authentication is only enabled once a first code from the app
+// has been confirmed.
+func (app *application) startTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	if user.TOTPEnabled {
+		writeJSONError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.StartTOTP(r.Context(), user.ID, secret); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	key, err := auth.ParseSecret(secret)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &TOTPEnrolmentResponse{
+		Secret: secret,
+		URI:    auth.NewTOTP(key).URI(app.config.mfa.issuer, user.Email),
+	})
+}
+
+// confirmTOTPHandler enables two-factor authentication with a first code
+// from the authenticator app and returns the recovery codes, which are not
+// shown again.
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ConfirmTOTPPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if user.TOTPEnabled {
+		writeJSONError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if user.TOTPSecret == "" {
+		writeJSONError(w, http.StatusBadRequest, "Two-factor enrolment has not been started")
+		return
+	}
+
+	key, err := auth.ParseSecret(user.TOTPSecret)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	step, ok := auth.NewTOTP(key).Verify(payload.Code, time.Now(), totpSkew)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.EnableTOTP(r.Context(), user.ID, step, hashes, time.Now()); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// disableTOTPHandler turns two-factor authentication off after checking a
+// second factor.
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.requireSecondFactor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.store.MFA.DisableTOTP(r.Context(), user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// regenerateRecoveryCodesHandler replaces the recovery codes after checking
+// a second factor.
+func (app *application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.requireSecondFactor(w, r)
+	if !ok {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.ReplaceRecoveryCodes(r.Context(), user.ID, hashes, time.Now()); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// requireSecondFactor reads a SecondFactorPayload and checks it for the
+// current user, who must have two-factor authentication enabled. It has
+// written the response when it returns false.
+func (app *application) requireSecondFactor(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	var payload SecondFactorPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return model.User{}, false
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return model.User{}, false
+	}
+
+	user := getUserFromContext(r)
+
+	if !user.TOTPEnabled {
+		writeJSONError(w, http.StatusConflict, "Two-factor authentication is not enabled")
+		return model.User{}, false
+	}
+
+	ok, err := app.checkSecondFactor(r.Context(), user, payload)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return model.User{}, false
+	}
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Invalid code")
+		return model.User{}, false
+	}
+
+	return user, true
+}
+
+// checkSecondFactor accepts a code from the user's authenticator app, at most
+// once, or uses up one of their recovery codes.
+func (app *application) checkSecondFactor(ctx context.Context, user model.User, payload SecondFactorPayload) (bool, error) {
+	if payload.RecoveryCode != "" {
+		err := app.store.MFA.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(payload.RecoveryCode)), time.Now())
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if payload.Code == "" {
+		return false, nil
+	}
+
+	key, err := auth.ParseSecret(user.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.NewTOTP(key).Verify(payload.Code, time.Now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	err = app.store.MFA.UseTOTPStep(ctx, user.ID, step)
+	if errors.Is(err, store.ErrCodeUsed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes to show the user,
+// formatted as xxxx-xxxx-xxxx-xxxx, together with the hashes to store. Each
+// code carries 80 random bits, so the stored hashes cannot be brute forced.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode undoes the formatting of a recovery code, so it can
+// be typed in any case and with or without the dash.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Join(strings.Fields(code), ""))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
 )
 
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
@@ -40,6 +43,12 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		sessionID, err := strconv.ParseUint(fmt.Sprintf("%.f", claims["sid"]), 10, 64)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
+			return
+		}
+
 		ctx := r.Context()
 
 		user, err := app.getUser(ctx, userID)
@@ -48,7 +57,28 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Access tokens stop working as soon as their session is signed out
+		now := time.Now()
+		session, err := app.store.Sessions.GetActive(ctx, user.ID, uint(sessionID), now)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				app.unauthorizedErrorResponse(w, r, fmt.Errorf("session has been signed out"))
+				return
+			}
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if now.Sub(session.LastUsedAt) >= sessionTouchInterval {
+			if err := app.store.Sessions.Touch(ctx, session.ID, now); err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			session.LastUsedAt = now
+		}
+
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, sessionCtx, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -65,10 +95,12 @@ func (app *application) getUser(ctx context.Context, userID int64) (model.User,
 	return user, nil
 }
 
+// RefreshTokenMiddleware passes the refresh token from the refresh_token
+// cookie on to the handler, which checks it when rotating it.
 func (app *application) RefreshTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract token from cookies
-		cookie, err := r.Cookie("refresh_token")
+		cookie, err := r.Cookie(refreshTokenCookie)
 		if err != nil {
 			if err == http.ErrNoCookie {
 				http.Error(w, "Missing refresh token", http.StatusUnauthorized)
@@ -78,29 +110,7 @@ func (app *application) RefreshTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		jwtToken, err := app.authenticator.ValidateToken(cookie.Value)
-		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
-			return
-		}
-
-		claims, _ := jwtToken.Claims.(jwt.MapClaims)
-
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
-		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
-			return
-		}
-
-		ctx := r.Context()
-		user, err := app.getUser(ctx, userID)
-		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
-			return
-		}
-
-		ctx = context.WithValue(ctx, userCtx, user)
-		// Token is valid, proceed to the next handler
+		ctx := withRefreshToken(r.Context(), cookie.Value)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/money"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type ExchangeRatePayload struct {
+	Date  string `json:"date" validate:"required,datetime=2006-01-02"`
+	Base  string `json:"base" validate:"required,iso4217"`
+	Quote string `json:"quote" validate:"required,iso4217,nefield=Base"`
+	Rate  string `json:"rate" validate:"required,max=40"`
+}
+
+type UpsertExchangeRatesPayload struct {
+	Rates []ExchangeRatePayload `json:"rates" validate:"required,min=1,max=1000,dive"`
+}
+
+// upsertExchangeRatesHandler stores a batch of rates. A rate is a decimal
+// string giving the value of one unit of base in quote, e.g. "1.0845"; a rate
+// already stored for the same pair and date is replaced, and so is an earlier
+// one for them in the same batch.
+func (app *application) upsertExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpsertExchangeRatesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	for i := range payload.Rates {
+		payload.Rates[i].Base = strings.ToUpper(payload.Rates[i].Base)
+		payload.Rates[i].Quote = strings.ToUpper(payload.Rates[i].Quote)
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	rates := make([]model.ExchangeRate, 0, len(payload.Rates))
+	for i, p := range payload.Rates {
+		if _, err := money.ParseRate(p.Rate); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("rates[%d]: rate must be a positive decimal number", i))
+			return
+		}
+
+		date, _ := time.Parse(dateLayout, p.Date)
+		rates = append(rates, model.ExchangeRate{
+			Date:  date,
+			Base:  p.Base,
+			Quote: p.Quote,
+			Rate:  strings.TrimSpace(p.Rate),
+		})
+	}
+
+	user := getUserFromContext(r)
+
+	stored, err := app.store.ExchangeRates.Upsert(r.Context(), user.ID, rates)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
+
+// listExchangeRatesHandler returns stored rates, optionally narrowed with the
+// base, quote, from and to query parameters.
+func (app *application) listExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := store.RateQuery{
+		Base:  query.Get("base"),
+		Quote: query.Get("quote"),
+	}
+
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(dateLayout, v)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		q.From = &from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(dateLayout, v)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		q.To = &to
+	}
+
+	user := getUserFromContext(r)
+
+	rates, err := app.store.ExchangeRates.List(r.Context(), user.ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rates)
+}
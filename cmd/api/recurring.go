@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type recurringKey string
+
+const recurringCtx recurringKey = "recurring"
+
+const maxUpcomingDays = 366
+
+// RecurringPayload is used both to create a recurring transaction and to
+// replace one.
+type RecurringPayload struct {
+	AccountID       uint    `json:"account_id" validate:"required"`
+	CategoryID      *uint   `json:"category_id"`
+	Amount          int64   `json:"amount" validate:"required"`
+	Payee           string  `json:"payee" validate:"max=255"`
+	Memo            string  `json:"memo" validate:"max=1000"`
+	Frequency       string  `json:"frequency" validate:"required,oneof=daily weekly monthly yearly"`
+	Interval        int     `json:"interval" validate:"omitempty,min=1,max=366"`
+	ByMonthDay      int     `json:"by_month_day" validate:"min=-1,max=31"`
+	LastBusinessDay bool    `json:"last_business_day"`
+	StartDate       string  `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate         *string `json:"end_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type OccurrencePayload struct {
+	Date string `json:"date" validate:"required,datetime=2006-01-02"`
+}
+
+func (p RecurringPayload) toRecurring(rt *model.RecurringTransaction) {
+	rt.AccountID = p.AccountID
+	rt.CategoryID = p.CategoryID
+	rt.Amount = p.Amount
+	rt.Payee = p.Payee
+	rt.Memo = p.Memo
+	rt.Frequency = p.Frequency
+	rt.Interval = max(p.Interval, 1)
+	rt.ByMonthDay = p.ByMonthDay
+	rt.LastBusinessDay = p.LastBusinessDay
+	rt.StartDate, _ = time.Parse(dateLayout, p.StartDate)
+	rt.EndDate = nil
+	if p.EndDate != nil {
+		end, _ := time.Parse(dateLayout, *p.EndDate)
+		rt.EndDate = &end
+	}
+}
+
+func (app *application) createRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RecurringPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	rt := &model.RecurringTransaction{UserID: user.ID}
+	payload.toRecurring(rt)
+
+	if err := app.store.Recurring.Create(r.Context(), rt); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rt)
+}
+
+func (app *application) listRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	rts, err := app.store.Recurring.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rts)
+}
+
+// upcomingRecurringHandler lists the occurrences due in the next days days,
+// 30 by default.
+func (app *application) upcomingRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUpcomingDays {
+			writeJSONError(w, http.StatusBadRequest, "days must be between 1 and 366")
+			return
+		}
+		days = n
+	}
+
+	user := getUserFromContext(r)
+	today := today()
+
+	occurrences, err := app.store.Recurring.Upcoming(r.Context(), user.ID, today, today.AddDate(0, 0, days))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, occurrences)
+}
+
+func (app *application) getRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	rt := getRecurringFromContext(r)
+
+	writeJSON(w, http.StatusOK, rt)
+}
+
+func (app *application) updateRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	rt := getRecurringFromContext(r)
+
+	var payload RecurringPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	payload.toRecurring(rt)
+
+	if err := app.store.Recurring.Update(r.Context(), rt); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rt)
+}
+
+func (app *application) deleteRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	rt := getRecurringFromContext(r)
+
+	if err := app.store.Recurring.Delete(r.Context(), rt.UserID, rt.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) skipOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	rt := getRecurringFromContext(r)
+
+	date, ok := app.readOccurrenceDate(w, r)
+	if !ok {
+		return
+	}
+
+	rt, err := app.store.Recurring.Skip(r.Context(), rt.UserID, rt.ID, date)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rt)
+}
+
+// postOccurrenceHandler posts an upcoming occurrence today instead of waiting
+// for the scheduler.
+func (app *application) postOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	rt := getRecurringFromContext(r)
+
+	date, ok := app.readOccurrenceDate(w, r)
+	if !ok {
+		return
+	}
+
+	txn, err := app.store.Recurring.PostEarly(r.Context(), rt.UserID, rt.ID, date, today())
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, txn)
+}
+
+func (app *application) readOccurrenceDate(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	var payload OccurrencePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return time.Time{}, false
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return time.Time{}, false
+	}
+
+	date, _ := time.Parse(dateLayout, payload.Date)
+	return date, true
+}
+
+func (app *application) recurringContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recurringID, err := strconv.ParseUint(chi.URLParam(r, "recurringID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		rt, err := app.store.Recurring.GetByID(ctx, user.ID, uint(recurringID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, recurringCtx, rt)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getRecurringFromContext(r *http.Request) *model.RecurringTransaction {
+	rt, _ := r.Context().Value(recurringCtx).(*model.RecurringTransaction)
+	return rt
+}
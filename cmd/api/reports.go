@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/budget"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+// maxReportSpan bounds how far apart from and to may be so a single report
+// cannot aggregate an unbounded number of buckets.
+const maxReportSpan = 10 * 366 * 24 * time.Hour
+
+// cashFlowReportHandler returns income and expense bucketed by interval and
+// grouped by group_by, with totals compared against the previous period. It
+// defaults to the last twelve months by month and category.
+func (app *application) cashFlowReportHandler(w http.ResponseWriter, r *http.Request) {
+	now := today()
+
+	q, err := parseReportQuery(r, store.ReportQuery{
+		From:     budget.MonthStart(now).AddDate(0, -11, 0),
+		To:       now,
+		Interval: "month",
+		GroupBy:  "category",
+	})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if q.To.Sub(q.From) > maxReportSpan {
+		writeJSONError(w, http.StatusBadRequest, "reports can span at most 10 years")
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	report, err := app.store.Reports.CashFlow(r.Context(), user.ID, q)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// netWorthReportHandler returns the net worth time series between from and
+// to, one point per interval. It defaults to the last twelve months by month.
+func (app *application) netWorthReportHandler(w http.ResponseWriter, r *http.Request) {
+	now := today()
+
+	q, err := parseReportQuery(r, store.ReportQuery{
+		From:     budget.MonthStart(now).AddDate(0, -11, 0),
+		To:       now,
+		Interval: "month",
+	})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if q.To.Sub(q.From) > maxReportSpan {
+		writeJSONError(w, http.StatusBadRequest, "reports can span at most 10 years")
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	series, err := app.store.NetWorth.Series(r.Context(), user.ID, q.From, q.To, q.Interval)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, series)
+}
+
+// parseReportQuery reads the report period, bucketing and filters in the
+// query string of r on top of the defaults already set on q.
+func parseReportQuery(r *http.Request, q store.ReportQuery) (store.ReportQuery, error) {
+	qs := r.URL.Query()
+
+	if v := qs.Get("interval"); v != "" {
+		q.Interval = v
+	}
+	if v := qs.Get("group_by"); v != "" {
+		q.GroupBy = v
+	}
+
+	from, err := parseDateParam(qs.Get("from"))
+	if err != nil {
+		return q, fmt.Errorf("invalid from: %w", err)
+	}
+	if from != nil {
+		q.From = *from
+	}
+
+	to, err := parseDateParam(qs.Get("to"))
+	if err != nil {
+		return q, fmt.Errorf("invalid to: %w", err)
+	}
+	if to != nil {
+		q.To = *to
+	}
+
+	if q.AccountID, err = parseUintParam(qs.Get("account_id")); err != nil {
+		return q, fmt.Errorf("invalid account_id: %w", err)
+	}
+	if q.TagID, err = parseUintParam(qs.Get("tag_id")); err != nil {
+		return q, fmt.Errorf("invalid tag_id: %w", err)
+	}
+
+	return q, nil
+}
+
+type BackfillResponse struct {
+	Snapshots int `json:"snapshots"`
+}
+
+// backfillNetWorthHandler rebuilds the user's net worth history from the
+// ledger up to yesterday, e.g. after importing old statements.
+func (app *application) backfillNetWorthHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	written, err := app.store.NetWorth.Backfill(r.Context(), user.ID, today().AddDate(0, 0, -1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &BackfillResponse{Snapshots: written})
+}
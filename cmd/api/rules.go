@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type ruleKey string
+
+const ruleCtx ruleKey = "rule"
+
+// RulePayload is used both to create a rule and to replace one.
+type RulePayload struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Priority int    `json:"priority"`
+	Enabled  *bool  `json:"enabled"`
+
+	PayeeMatch   string `json:"payee_match" validate:"omitempty,oneof=contains regex exact"`
+	PayeePattern string `json:"payee_pattern" validate:"max=255"`
+	MinAmount    *int64 `json:"min_amount"`
+	MaxAmount    *int64 `json:"max_amount"`
+	AccountID    *uint  `json:"account_id"`
+	MemoContains string `json:"memo_contains" validate:"max=255"`
+
+	SetCategoryID *uint    `json:"set_category_id"`
+	RenamePayee   string   `json:"rename_payee" validate:"max=255"`
+	AddTags       []string `json:"add_tags" validate:"max=20,dive,required,max=50"`
+	MarkTransfer  bool     `json:"mark_transfer"`
+}
+
+type ApplyRuleResponse struct {
+	DryRun  bool               `json:"dry_run"`
+	Count   int                `json:"count"`
+	Changes []store.RuleChange `json:"changes"`
+}
+
+func (p RulePayload) toRule(rule *model.Rule) {
+	rule.Name = p.Name
+	rule.Priority = p.Priority
+	rule.Enabled = p.Enabled == nil || *p.Enabled
+	rule.PayeeMatch = model.PayeeMatch(p.PayeeMatch)
+	rule.PayeePattern = p.PayeePattern
+	rule.MinAmount = p.MinAmount
+	rule.MaxAmount = p.MaxAmount
+	rule.AccountID = p.AccountID
+	rule.MemoContains = p.MemoContains
+	rule.SetCategoryID = p.SetCategoryID
+	rule.RenamePayee = p.RenamePayee
+	rule.AddTags = p.AddTags
+	rule.MarkTransfer = p.MarkTransfer
+}
+
+func (app *application) createRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	rule := &model.Rule{UserID: user.ID}
+	payload.toRule(rule)
+
+	if err := app.store.Rules.Create(r.Context(), rule); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (app *application) listRulesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	rs, err := app.store.Rules.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rs)
+}
+
+func (app *application) getRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRuleFromContext(r)
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (app *application) updateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRuleFromContext(r)
+
+	var payload RulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	payload.toRule(rule)
+
+	if err := app.store.Rules.Update(r.Context(), rule); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (app *application) deleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRuleFromContext(r)
+
+	if err := app.store.Rules.Delete(r.Context(), rule.UserID, rule.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyRuleHandler runs a rule over existing transactions. It defaults to a
+// dry run that only previews the changes; pass dry_run=false to write them.
+// The transaction list filters (account_id, from, to, search, ...) narrow
+// down which transactions are considered.
+func (app *application) applyRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRuleFromContext(r)
+
+	dryRun := true
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		dryRun = parsed
+	}
+
+	q, err := parseTransactionQuery(r, store.TransactionQuery{})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	changes, err := app.store.Rules.Apply(r.Context(), rule.UserID, rule.ID, q, dryRun)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &ApplyRuleResponse{
+		DryRun:  dryRun,
+		Count:   len(changes),
+		Changes: changes,
+	})
+}
+
+func (app *application) rulesContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ruleID, err := strconv.ParseUint(chi.URLParam(r, "ruleID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		rule, err := app.store.Rules.GetByID(ctx, user.ID, uint(ruleID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, ruleCtx, rule)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getRuleFromContext(r *http.Request) *model.Rule {
+	rule, _ := r.Context().Value(ruleCtx).(*model.Rule)
+	return rule
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/goal"
+	"github.com/nelsonfrank/finance-tracker/internal/notify"
+)
+
+// runScheduler posts due recurring transactions, takes net worth snapshots and
+// checks savings goals once at start-up and then on every tick until ctx is
+// cancelled. Every replica runs it; the store makes sure only one of them does
+// each job at a time.
+func (app *application) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(app.config.scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		app.postDueRecurring(ctx)
+		app.snapshotNetWorth(ctx)
+		app.checkGoals(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) postDueRecurring(ctx context.Context) {
+	// Templates that failed are reported without losing the ones posted
+	posted, err := app.store.Recurring.PostDue(ctx, today())
+	if err != nil {
+		app.logger.Errorw("posting recurring transactions", "error", err.Error())
+	}
+
+	if posted > 0 {
+		app.logger.Infow("posted recurring transactions", "count", posted)
+	}
+}
+
+// snapshotNetWorth records yesterday's closing balances. Today's are taken
+// tomorrow, once the day can no longer change.
+func (app *application) snapshotNetWorth(ctx context.Context) {
+	written, err := app.store.NetWorth.SnapshotDue(ctx, today().AddDate(0, 0, -1))
+	if err != nil {
+		app.logger.Errorw("taking net worth snapshots", "error", err.Error())
+		return
+	}
+
+	if written > 0 {
+		app.logger.Infow("took net worth snapshots", "count", written)
+	}
+}
+
+// checkGoals re-evaluates every savings goal and emits a notification for
+// each one that has just been reached or fallen behind.
+func (app *application) checkGoals(ctx context.Context) {
+	transitions, err := app.store.Goals.CheckAll(ctx, today())
+	if err != nil {
+		app.logger.Errorw("checking goals", "error", err.Error())
+		return
+	}
+
+	for _, t := range transitions {
+		var kind notify.Kind
+		switch t.Progress.Status {
+		case goal.StatusReached:
+			kind = notify.GoalReached
+		case goal.StatusBehind:
+			kind = notify.GoalBehind
+		default:
+			continue
+		}
+
+		event := notify.Event{
+			Kind:    kind,
+			UserID:  t.UserID,
+			Subject: t.Name,
+			Data: map[string]any{
+				"goal_id":  t.ID,
+				"currency": t.Currency,
+				"progress": t.Progress,
+			},
+			At: time.Now(),
+		}
+		if err := app.notifier.Emit(ctx, event); err != nil {
+			app.logger.Errorw("emitting goal notification", "goal_id", t.ID, "error", err.Error())
+		}
+	}
+}
+
+// today is the current calendar date in UTC.
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type sessionKey string
+
+const sessionCtx sessionKey = "session"
+
+// sessionTouchInterval is how stale a session's last-used time may get before
+// an authenticated request updates it, so not every request writes to it.
+const sessionTouchInterval = 5 * time.Minute
+
+// SessionResponse is a device the user is signed in on. Location is the
+// network its last address belongs to, a /24 for IPv4 and a /48 for IPv6,
+// which is as precise as an address gets without a GeoIP database.
+type SessionResponse struct {
+	model.Session
+	Location string `json:"location"`
+	Current  bool   `json:"current"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+func (app *application) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	current := getSessionFromContext(r)
+
+	sessions, err := app.store.Sessions.List(r.Context(), user.ID, time.Now())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		response = append(response, SessionResponse{
+			Session:  s,
+			Location: approximateLocation(s.IP),
+			Current:  current != nil && s.ID == current.ID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// deleteSessionHandler signs the device of one session out. Its refresh
+// token stops working right away and so do its access tokens.
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.ParseUint(chi.URLParam(r, "sessionID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Sessions.Revoke(r.Context(), user.ID, uint(sessionID), time.Now()); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	if current := getSessionFromContext(r); current != nil && current.ID == uint(sessionID) {
+		app.clearRefreshTokenCookie(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteOtherSessionsHandler signs out everywhere except the session the
+// request is made from.
+func (app *application) deleteOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	current := getSessionFromContext(r)
+
+	revoked, err := app.store.Sessions.RevokeOthers(r.Context(), user.ID, current.ID, time.Now())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &RevokeSessionsResponse{Revoked: revoked})
+}
+
+func approximateLocation(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+
+	mask := net.CIDRMask(48, 128)
+	if ip.To4() != nil {
+		mask = net.CIDRMask(24, 32)
+	}
+
+	network := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	return network.String()
+}
+
+func getSessionFromContext(r *http.Request) *model.Session {
+	session, _ := r.Context().Value(sessionCtx).(*model.Session)
+	return session
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type tagKey string
+
+const tagCtx tagKey = "tag"
+
+type TagPayload struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+// BulkTagPayload lists tag names to add to and remove from every transaction
+// matching the filters in the query string.
+type BulkTagPayload struct {
+	Add    []string `json:"add" validate:"max=20,dive,required,max=50"`
+	Remove []string `json:"remove" validate:"max=20,dive,required,max=50"`
+}
+
+type BulkTagResponse struct {
+	Added   int64 `json:"added"`
+	Removed int64 `json:"removed"`
+}
+
+func (app *application) createTagHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	tag := &model.Tag{UserID: user.ID, Name: payload.Name}
+
+	if err := app.store.Tags.Create(r.Context(), tag); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tag)
+}
+
+func (app *application) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	tags, err := app.store.Tags.List(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func (app *application) getTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromContext(r)
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromContext(r)
+
+	var payload TagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	tag.Name = payload.Name
+
+	if err := app.store.Tags.Update(r.Context(), tag); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+func (app *application) deleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromContext(r)
+
+	if err := app.store.Tags.Delete(r.Context(), tag.UserID, tag.ID); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bulkTagHandler adds and removes tags on every transaction matching the
+// same filters the transaction list takes. Tags being added are created when
+// the user does not have them yet.
+func (app *application) bulkTagHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseTransactionQuery(r, store.TransactionQuery{})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload BulkTagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	for i := range payload.Add {
+		payload.Add[i] = strings.TrimSpace(payload.Add[i])
+	}
+	for i := range payload.Remove {
+		payload.Remove[i] = strings.TrimSpace(payload.Remove[i])
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if len(payload.Add) == 0 && len(payload.Remove) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "add or remove at least one tag")
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	var resp BulkTagResponse
+	if len(payload.Remove) > 0 {
+		if resp.Removed, err = app.store.Tags.Untag(r.Context(), user.ID, q, payload.Remove); err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+	}
+	if len(payload.Add) > 0 {
+		if resp.Added, err = app.store.Tags.Tag(r.Context(), user.ID, q, payload.Add); err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, &resp)
+}
+
+func (app *application) tagsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := strconv.ParseUint(chi.URLParam(r, "tagID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		tag, err := app.store.Tags.GetByID(ctx, user.ID, uint(tagID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, tagCtx, tag)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getTagFromContext(r *http.Request) *model.Tag {
+	tag, _ := r.Context().Value(tagCtx).(*model.Tag)
+	return tag
+}
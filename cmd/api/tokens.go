@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+type refreshTokenKey string
+
+const refreshTokenCtx refreshTokenKey = "refresh_token"
+
+// refreshTokenCookie is the cookie the refresh token is kept in. It is only
+// sent to the auth endpoints that need it.
+const (
+	refreshTokenCookie     = "refresh_token"
+	refreshTokenCookiePath = "/v1/auth"
+)
+
+// maxUserAgentLen bounds the user agent stored for a session.
+const maxUserAgentLen = 512
+
+// newAccessToken returns an access token for the user that is only valid as
+// long as the session it was issued for.
+func (app *application) newAccessToken(userID, sessionID uint) (string, error) {
+	claims := app.authenticator.JwtClaimGenerator(
+		userID,
+		app.config.mfa.token.exp,
+		app.config.mfa.token.iss,
+		app.config.mfa.token.iss,
+	).(jwt.MapClaims)
+	claims["sid"] = sessionID
+
+	return app.authenticator.GenerateToken(claims)
+}
+
+// newRefreshToken returns a new refresh token for the device making the
+// request together with the record to store for it. The store fills in the
+// user and session.
+func (app *application) newRefreshToken(r *http.Request) (string, *model.RefreshToken, error) {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+
+	now := time.Now()
+	return token, &model.RefreshToken{
+		TokenHash: tokenHash,
+		UserAgent: userAgent,
+		IP:        clientIP(r),
+		ExpiresAt: now.Add(app.config.mfa.token.refreshTokenExp),
+		CreatedAt: now,
+	}, nil
+}
+
+func (app *application) setRefreshTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    token,
+		Path:     refreshTokenCookiePath,
+		MaxAge:   int(app.config.mfa.token.refreshTokenExp.Seconds()),
+		HttpOnly: true,
+		Secure:   app.config.env == "production",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (app *application) clearRefreshTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    "",
+		Path:     refreshTokenCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   app.config.env == "production",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clientIP returns the address of the client. middleware.RealIP has already
+// replaced RemoteAddr with the forwarded address when there is one, which
+// comes without a port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func getRefreshTokenFromContext(r *http.Request) string {
+	token, _ := r.Context().Value(refreshTokenCtx).(string)
+	return token
+}
+
+func withRefreshToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, refreshTokenCtx, token)
+}
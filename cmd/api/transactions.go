@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type transactionKey string
+
+const transactionCtx transactionKey = "transaction"
+
+const dateLayout = "2006-01-02"
+
+type CreateTransactionPayload struct {
+	AccountID  uint   `json:"account_id" validate:"required"`
+	CategoryID *uint  `json:"category_id"`
+	Amount     int64  `json:"amount" validate:"required"`
+	Date       string `json:"date" validate:"required,datetime=2006-01-02"`
+	Payee      string `json:"payee" validate:"max=255"`
+	Memo       string `json:"memo" validate:"max=1000"`
+
+	Splits []SplitPayload `json:"splits" validate:"omitempty,min=2,max=50,dive"`
+}
+
+// SplitPayload is one line of a split transaction. The lines of a
+// transaction must add up to its amount.
+type SplitPayload struct {
+	CategoryID *uint  `json:"category_id"`
+	Amount     int64  `json:"amount" validate:"required"`
+	Memo       string `json:"memo" validate:"max=1000"`
+}
+
+// SetSplitsPayload replaces the lines of a transaction; an empty list
+// removes the split.
+type SetSplitsPayload struct {
+	Splits []SplitPayload `json:"splits" validate:"max=50,dive"`
+}
+
+type UpdateTransactionPayload struct {
+	AccountID  *uint   `json:"account_id" validate:"omitempty,gt=0"`
+	CategoryID *uint   `json:"category_id"`
+	Amount     *int64  `json:"amount" validate:"omitempty,ne=0"`
+	Date       *string `json:"date" validate:"omitempty,datetime=2006-01-02"`
+	Payee      *string `json:"payee" validate:"omitempty,max=255"`
+	Memo       *string `json:"memo" validate:"omitempty,max=1000"`
+}
+
+type TransactionListResponse struct {
+	Data       []model.Transaction `json:"data"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+func (app *application) createTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateTransactionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	date, _ := time.Parse(dateLayout, payload.Date)
+	user := getUserFromContext(r)
+
+	txn := &model.Transaction{
+		UserID:     user.ID,
+		AccountID:  payload.AccountID,
+		CategoryID: payload.CategoryID,
+		Amount:     payload.Amount,
+		Date:       date,
+		Payee:      payload.Payee,
+		Memo:       payload.Memo,
+		Splits:     splitLines(payload.Splits),
+	}
+
+	if err := app.store.Transactions.Create(r.Context(), txn); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, txn)
+}
+
+func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseTransactionQuery(r, store.TransactionQuery{Limit: 50})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	transactions, next, err := app.store.Transactions.List(r.Context(), user.ID, q)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &TransactionListResponse{
+		Data:       transactions,
+		NextCursor: next,
+	})
+}
+
+func (app *application) getTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	txn := getTransactionFromContext(r)
+
+	writeJSON(w, http.StatusOK, txn)
+}
+
+func (app *application) updateTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	txn := getTransactionFromContext(r)
+
+	var payload UpdateTransactionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	if payload.AccountID != nil {
+		txn.AccountID = *payload.AccountID
+	}
+	if payload.CategoryID != nil {
+		txn.CategoryID = payload.CategoryID
+	}
+	if payload.Amount != nil {
+		txn.Amount = *payload.Amount
+	}
+	if payload.Date != nil {
+		txn.Date, _ = time.Parse(dateLayout, *payload.Date)
+	}
+	if payload.Payee != nil {
+		txn.Payee = *payload.Payee
+	}
+	if payload.Memo != nil {
+		txn.Memo = *payload.Memo
+	}
+
+	if err := app.store.Transactions.Update(r.Context(), txn); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, txn)
+}
+
+func (app *application) deleteTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	txn := getTransactionFromContext(r)
+
+	keys, err := app.store.Transactions.Delete(r.Context(), txn.UserID, txn.ID)
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	for _, key := range keys {
+		app.deleteBlob(r.Context(), key)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setTransactionSplitsHandler splits a transaction across categories, or
+// merges it back into one uncategorised transaction when given no lines.
+func (app *application) setTransactionSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	txn := getTransactionFromContext(r)
+
+	var payload SetSplitsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	updated, err := app.store.Transactions.SetSplits(r.Context(), txn.UserID, txn.ID, splitLines(payload.Splits))
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func splitLines(payload []SplitPayload) []model.TransactionSplit {
+	var lines []model.TransactionSplit
+	for _, p := range payload {
+		lines = append(lines, model.TransactionSplit{
+			CategoryID: p.CategoryID,
+			Amount:     p.Amount,
+			Memo:       p.Memo,
+		})
+	}
+	return lines
+}
+
+// parseTransactionQuery reads the transaction filters in the query string of
+// r on top of the defaults already set on q.
+func parseTransactionQuery(r *http.Request, q store.TransactionQuery) (store.TransactionQuery, error) {
+	qs := r.URL.Query()
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, fmt.Errorf("invalid limit: %w", err)
+		}
+		q.Limit = l
+	}
+
+	q.Cursor = qs.Get("cursor")
+	q.Search = strings.TrimSpace(qs.Get("search"))
+
+	var err error
+	if q.AccountID, err = parseUintParam(qs.Get("account_id")); err != nil {
+		return q, fmt.Errorf("invalid account_id: %w", err)
+	}
+	if q.CategoryID, err = parseUintParam(qs.Get("category_id")); err != nil {
+		return q, fmt.Errorf("invalid category_id: %w", err)
+	}
+	if q.TagID, err = parseUintParam(qs.Get("tag_id")); err != nil {
+		return q, fmt.Errorf("invalid tag_id: %w", err)
+	}
+	if q.From, err = parseDateParam(qs.Get("from")); err != nil {
+		return q, fmt.Errorf("invalid from: %w", err)
+	}
+	if q.To, err = parseDateParam(qs.Get("to")); err != nil {
+		return q, fmt.Errorf("invalid to: %w", err)
+	}
+	if q.MinAmount, err = parseIntParam(qs.Get("min_amount")); err != nil {
+		return q, fmt.Errorf("invalid min_amount: %w", err)
+	}
+	if q.MaxAmount, err = parseIntParam(qs.Get("max_amount")); err != nil {
+		return q, fmt.Errorf("invalid max_amount: %w", err)
+	}
+
+	return q, nil
+}
+
+func parseUintParam(s string) (*uint, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	v := uint(n)
+	return &v, nil
+}
+
+func parseIntParam(s string) (*int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+func parseDateParam(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (app *application) transactionsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		transactionID, err := strconv.ParseUint(chi.URLParam(r, "transactionID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUserFromContext(r)
+
+		ctx := r.Context()
+
+		txn, err := app.store.Transactions.GetByID(ctx, user.ID, uint(transactionID))
+		if err != nil {
+			app.storeErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, transactionCtx, txn)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getTransactionFromContext(r *http.Request) *model.Transaction {
+	txn, _ := r.Context().Value(transactionCtx).(*model.Transaction)
+	return txn
+}
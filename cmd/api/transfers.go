@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/store"
+)
+
+type CreateTransferPayload struct {
+	FromAccountID uint   `json:"from_account_id" validate:"required"`
+	ToAccountID   uint   `json:"to_account_id" validate:"required,nefield=FromAccountID"`
+	Amount        int64  `json:"amount" validate:"required,gt=0"`
+	Date          string `json:"date" validate:"required,datetime=2006-01-02"`
+	Memo          string `json:"memo" validate:"max=1000"`
+}
+
+type TransferResponse struct {
+	From *model.Transaction `json:"from"`
+	To   *model.Transaction `json:"to"`
+}
+
+func (app *application) createTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateTransferPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	date, _ := time.Parse(dateLayout, payload.Date)
+	user := getUserFromContext(r)
+
+	from, to, err := app.store.Transactions.CreateTransfer(r.Context(), store.Transfer{
+		UserID:        user.ID,
+		FromAccountID: payload.FromAccountID,
+		ToAccountID:   payload.ToAccountID,
+		Amount:        payload.Amount,
+		Date:          date,
+		Memo:          payload.Memo,
+	})
+	if err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, &TransferResponse{From: from, To: to})
+}
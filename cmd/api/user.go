@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/nelsonfrank/finance-tracker/internal/db/model"
 )
@@ -14,3 +15,38 @@ func getUserFromContext(r *http.Request) model.User {
 	user, _ := r.Context().Value(userCtx).(model.User)
 	return user
 }
+
+type UpdateSettingsPayload struct {
+	HomeCurrency string `json:"home_currency" validate:"required,iso4217"`
+}
+
+func (app *application) getCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, getUserFromContext(r))
+}
+
+// updateSettingsHandler changes the authenticated user's preferences. Only the
+// home currency, which reports and the dashboard convert into, exists today.
+func (app *application) updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateSettingsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.HomeCurrency = strings.ToUpper(payload.HomeCurrency)
+
+	if err := Validate.Struct(payload); err != nil {
+		sendError(w, http.StatusBadRequest, app.validationErrorFormatter(err))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Users.SetHomeCurrency(r.Context(), user.ID, payload.HomeCurrency); err != nil {
+		app.storeErrorResponse(w, r, err)
+		return
+	}
+
+	user.HomeCurrency = payload.HomeCurrency
+	writeJSON(w, http.StatusOK, user)
+}
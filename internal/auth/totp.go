@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm is the HMAC hash a TOTP is computed with.
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+const (
+	// DefaultDigits and DefaultPeriod are what authenticator apps assume
+	// when an otpauth URI does not say otherwise.
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+
+	// secretSize is the length of generated secrets, the 160 bits RFC 4226
+	// recommends for SHA-1.
+	secretSize = 20
+)
+
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP computes time-based one-time passwords as specified by RFC 6238: the
+// HOTP value of RFC 4226 for the number of periods since the Unix epoch.
+type TOTP struct {
+	Secret    []byte
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+}
+
+// NewTOTP returns a TOTP for secret with the settings every authenticator
+// app supports: SHA-1, six digits and a 30 second period.
+func NewTOTP(secret []byte) TOTP {
+	return TOTP{
+		Secret:    secret,
+		Algorithm: SHA1,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+}
+
+// GenerateSecret returns a new random secret, base32 encoded without padding
+// the way authenticator apps expect it.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return secretEncoding.EncodeToString(b), nil
+}
+
+// ParseSecret decodes a base32 secret, ignoring case, spaces and padding.
+func ParseSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Join(strings.Fields(secret), ""))
+	secret = strings.TrimRight(secret, "=")
+
+	b, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(b) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	return b, nil
+}
+
+// Step returns the time step at falls in.
+func (t TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period/time.Second)
+}
+
+// Code returns the code for the time step at falls in.
+func (t TOTP) Code(at time.Time) string {
+	return t.codeAt(t.Step(at))
+}
+
+// Verify checks code against the time step at falls in and the skew steps
+// either side of it, to allow for clock drift and typing time. It returns
+// the step that matched so callers can refuse to accept it twice.
+func (t TOTP) Verify(code string, at time.Time, skew int) (int64, bool) {
+	if len(code) != t.Digits {
+		return 0, false
+	}
+
+	current := t.Step(at)
+	for i := -int64(skew); i <= int64(skew); i++ {
+		step := current + i
+		if subtle.ConstantTimeCompare([]byte(t.codeAt(step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth:// URI authenticator apps enrol from, usually
+// shown as a QR code.
+func (t TOTP) URI(issuer, account string) string {
+	v := url.Values{}
+	v.Set("secret", secretEncoding.EncodeToString(t.Secret))
+	v.Set("issuer", issuer)
+	v.Set("algorithm", string(t.Algorithm))
+	v.Set("digits", strconv.Itoa(t.Digits))
+	v.Set("period", strconv.Itoa(int(t.Period/time.Second)))
+
+	// Key URIs spell spaces %20 in the query too; a literal + is already
+	// escaped as %2B by Encode.
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(v.Encode(), "+", "%20")
+}
+
+// codeAt is HOTP (RFC 4226 section 5.3) with the time step as counter.
+func (t TOTP) codeAt(step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(t.Algorithm.hash(), t.Secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+
+	modulus := uint64(1)
+	for i := 0; i < t.Digits; i++ {
+		modulus *= 10
+	}
+
+	return fmt.Sprintf("%0*d", t.Digits, value%modulus)
+}
+
+func (a Algorithm) hash() func() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test vectors of RFC 6238 appendix B. Each algorithm uses the ASCII
+// seed "12345678901234567890" repeated to the length of its hash.
+var rfc6238Seeds = map[Algorithm]string{
+	SHA1:   "12345678901234567890",
+	SHA256: "12345678901234567890123456789012",
+	SHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix      int64
+		algorithm Algorithm
+		want      string
+	}{
+		{59, SHA1, "94287082"},
+		{59, SHA256, "46119246"},
+		{59, SHA512, "90693936"},
+		{1111111109, SHA1, "07081804"},
+		{1111111109, SHA256, "68084774"},
+		{1111111109, SHA512, "25091201"},
+		{1111111111, SHA1, "14050471"},
+		{1111111111, SHA256, "67062674"},
+		{1111111111, SHA512, "99943326"},
+		{1234567890, SHA1, "89005924"},
+		{1234567890, SHA256, "91819424"},
+		{1234567890, SHA512, "93441116"},
+		{2000000000, SHA1, "69279037"},
+		{2000000000, SHA256, "90698825"},
+		{2000000000, SHA512, "38618901"},
+		{20000000000, SHA1, "65353130"},
+		{20000000000, SHA256, "77737706"},
+		{20000000000, SHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		totp := TOTP{
+			Secret:    []byte(rfc6238Seeds[tt.algorithm]),
+			Algorithm: tt.algorithm,
+			Digits:    8,
+			Period:    30 * time.Second,
+		}
+
+		at := time.Unix(tt.unix, 0).UTC()
+		if got := totp.Code(at); got != tt.want {
+			t.Errorf("%s at %d = %s, want %s", tt.algorithm, tt.unix, got, tt.want)
+		}
+		if _, ok := totp.Verify(tt.want, at, 0); !ok {
+			t.Errorf("%s at %d: Verify(%s) failed", tt.algorithm, tt.unix, tt.want)
+		}
+	}
+}
+
+func TestTOTPVerifySkew(t *testing.T) {
+	totp := NewTOTP([]byte(rfc6238Seeds[SHA1]))
+	at := time.Unix(1111111111, 0)
+	previous := totp.Code(at.Add(-totp.Period))
+
+	if _, ok := totp.Verify(previous, at, 0); ok {
+		t.Errorf("code of the previous step accepted without skew")
+	}
+
+	step, ok := totp.Verify(previous, at, 1)
+	if !ok {
+		t.Fatalf("code of the previous step rejected with a skew of 1")
+	}
+	if want := totp.Step(at) - 1; step != want {
+		t.Errorf("matched step = %d, want %d", step, want)
+	}
+
+	if _, ok := totp.Verify(totp.Code(at.Add(2*totp.Period)), at, 1); ok {
+		t.Errorf("code two steps ahead accepted with a skew of 1")
+	}
+	if _, ok := totp.Verify("", at, 1); ok {
+		t.Errorf("empty code accepted")
+	}
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	b, err := ParseSecret(strings.ToLower(secret[:4] + " " + secret[4:]))
+	if err != nil {
+		t.Fatalf("ParseSecret() error = %v", err)
+	}
+	if len(b) != secretSize {
+		t.Errorf("secret is %d bytes, want %d", len(b), secretSize)
+	}
+
+	if _, err := ParseSecret("not base32!"); err != ErrInvalidSecret {
+		t.Errorf("ParseSecret() error = %v, want %v", err, ErrInvalidSecret)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	totp := NewTOTP([]byte(rfc6238Seeds[SHA1]))
+
+	got := totp.URI("Finance Tracker", "jane@example.com")
+	want := "otpauth://totp/Finance%20Tracker:jane@example.com" +
+		"?algorithm=SHA1&digits=6&issuer=Finance%20Tracker&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	if got != want {
+		t.Errorf("URI() = %s, want %s", got, want)
+	}
+}
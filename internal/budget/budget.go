@@ -0,0 +1,106 @@
+// Package budget computes the status of monthly category budgets from the
+// amounts budgeted and spent. It has no database dependencies so the rules
+// for rollover and over-spend can be exercised in isolation.
+package budget
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Period is one month of a single category's budget.
+type Period struct {
+	Month    time.Time
+	Budgeted int64
+	Spent    int64
+	Rollover bool
+}
+
+// Status is the computed state of a Period.
+type Status struct {
+	Month       time.Time `json:"month"`
+	Budgeted    int64     `json:"budgeted"`
+	Carried     int64     `json:"carried"`
+	Available   int64     `json:"available"`
+	Spent       int64     `json:"spent"`
+	Remaining   int64     `json:"remaining"`
+	PercentUsed float64   `json:"percent_used"`
+	Overspent   bool      `json:"overspent"`
+}
+
+// MonthStart truncates t to midnight UTC on the first day of its month.
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Compute returns the status of every period of one category, in
+// chronological order. A period with Rollover set starts with whatever
+// remained in the period for the immediately preceding month, which may be
+// negative when that month was overspent. A gap of one or more months without
+// a budget breaks the chain.
+func Compute(periods []Period) []Status {
+	sorted := make([]Period, len(periods))
+	copy(sorted, periods)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Month.Before(sorted[j].Month)
+	})
+
+	statuses := make([]Status, 0, len(sorted))
+	for i, p := range sorted {
+		month := MonthStart(p.Month)
+
+		var carried int64
+		if p.Rollover && i > 0 {
+			prev := statuses[i-1]
+			if prev.Month.AddDate(0, 1, 0).Equal(month) {
+				carried = prev.Remaining
+			}
+		}
+
+		available := p.Budgeted + carried
+		remaining := available - p.Spent
+
+		statuses = append(statuses, Status{
+			Month:       month,
+			Budgeted:    p.Budgeted,
+			Carried:     carried,
+			Available:   available,
+			Spent:       p.Spent,
+			Remaining:   remaining,
+			PercentUsed: percentUsed(p.Spent, available),
+			Overspent:   remaining < 0,
+		})
+	}
+
+	return statuses
+}
+
+// percentUsed is spent as a percentage of available, rounded to one decimal.
+// Anything spent against a zero or negative allowance counts as 100% or more.
+func percentUsed(spent, available int64) float64 {
+	if available <= 0 {
+		if spent > 0 {
+			return 100
+		}
+		return 0
+	}
+
+	return math.Round(float64(spent)*1000/float64(available)) / 10
+}
+
+// Rollup adds the spending recorded against each category to all of its
+// ancestors, so a budget on Food also covers Food > Groceries. parents maps a
+// category to its parent; top-level categories are absent or map to nil.
+func Rollup(spent map[uint]int64, parents map[uint]*uint) map[uint]int64 {
+	total := make(map[uint]int64, len(spent))
+	for id, amount := range spent {
+		seen := map[uint]bool{}
+		for cur := &id; cur != nil && !seen[*cur]; cur = parents[*cur] {
+			seen[*cur] = true
+			total[*cur] += amount
+		}
+	}
+
+	return total
+}
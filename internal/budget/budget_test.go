@@ -0,0 +1,199 @@
+package budget
+
+import (
+	"testing"
+	"time"
+)
+
+func month(m time.Month) time.Time {
+	return time.Date(2026, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name    string
+		periods []Period
+		want    []Status
+	}{
+		{
+			name:    "no budgets",
+			periods: nil,
+			want:    []Status{},
+		},
+		{
+			name:    "under budget",
+			periods: []Period{{Month: month(time.January), Budgeted: 10000, Spent: 2500}},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 10000, Available: 10000, Spent: 2500, Remaining: 7500, PercentUsed: 25},
+			},
+		},
+		{
+			name:    "overspent",
+			periods: []Period{{Month: month(time.January), Budgeted: 10000, Spent: 12000}},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 10000, Available: 10000, Spent: 12000, Remaining: -2000, PercentUsed: 120, Overspent: true},
+			},
+		},
+		{
+			name: "rollover carries what is left",
+			periods: []Period{
+				{Month: month(time.January), Budgeted: 10000, Spent: 6000},
+				{Month: month(time.February), Budgeted: 10000, Rollover: true},
+			},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 10000, Available: 10000, Spent: 6000, Remaining: 4000, PercentUsed: 60},
+				{Month: month(time.February), Budgeted: 10000, Carried: 4000, Available: 14000, Remaining: 14000},
+			},
+		},
+		{
+			name: "rollover carries an overspend",
+			periods: []Period{
+				{Month: month(time.January), Budgeted: 10000, Spent: 12000},
+				{Month: month(time.February), Budgeted: 10000, Spent: 5000, Rollover: true},
+			},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 10000, Available: 10000, Spent: 12000, Remaining: -2000, PercentUsed: 120, Overspent: true},
+				{Month: month(time.February), Budgeted: 10000, Carried: -2000, Available: 8000, Spent: 5000, Remaining: 3000, PercentUsed: 62.5},
+			},
+		},
+		{
+			name: "without rollover nothing is carried",
+			periods: []Period{
+				{Month: month(time.January), Budgeted: 10000, Spent: 6000},
+				{Month: month(time.February), Budgeted: 10000, Spent: 1000},
+			},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 10000, Available: 10000, Spent: 6000, Remaining: 4000, PercentUsed: 60},
+				{Month: month(time.February), Budgeted: 10000, Available: 10000, Spent: 1000, Remaining: 9000, PercentUsed: 10},
+			},
+		},
+		{
+			name: "a month without a budget breaks the chain",
+			periods: []Period{
+				{Month: month(time.January), Budgeted: 10000, Spent: 6000},
+				{Month: month(time.March), Budgeted: 10000, Rollover: true},
+			},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 10000, Available: 10000, Spent: 6000, Remaining: 4000, PercentUsed: 60},
+				{Month: month(time.March), Budgeted: 10000, Available: 10000, Remaining: 10000},
+			},
+		},
+		{
+			name: "periods are sorted and truncated to the month",
+			periods: []Period{
+				{Month: time.Date(2026, time.February, 14, 9, 30, 0, 0, time.UTC), Budgeted: 300, Spent: 100, Rollover: true},
+				{Month: time.Date(2026, time.January, 20, 0, 0, 0, 0, time.UTC), Budgeted: 300, Spent: 200},
+			},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 300, Available: 300, Spent: 200, Remaining: 100, PercentUsed: 66.7},
+				{Month: month(time.February), Budgeted: 300, Carried: 100, Available: 400, Spent: 100, Remaining: 300, PercentUsed: 25},
+			},
+		},
+		{
+			name:    "zero allowance with spending",
+			periods: []Period{{Month: month(time.January), Spent: 500}},
+			want: []Status{
+				{Month: month(time.January), Spent: 500, Remaining: -500, PercentUsed: 100, Overspent: true},
+			},
+		},
+		{
+			name:    "zero allowance without spending",
+			periods: []Period{{Month: month(time.January)}},
+			want: []Status{
+				{Month: month(time.January)},
+			},
+		},
+		{
+			name: "negative allowance after a large overspend",
+			periods: []Period{
+				{Month: month(time.January), Budgeted: 100, Spent: 1000},
+				{Month: month(time.February), Budgeted: 500, Rollover: true},
+			},
+			want: []Status{
+				{Month: month(time.January), Budgeted: 100, Available: 100, Spent: 1000, Remaining: -900, PercentUsed: 1000, Overspent: true},
+				{Month: month(time.February), Budgeted: 500, Carried: -900, Available: -400, Remaining: -400, Overspent: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.periods)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Compute() returned %d statuses, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("status %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPercentUsed(t *testing.T) {
+	tests := []struct {
+		spent, available int64
+		want             float64
+	}{
+		{0, 1000, 0},
+		{1, 3, 33.3},
+		{2, 3, 66.7},
+		{1000, 1000, 100},
+		{1500, 1000, 150},
+		{-200, 1000, -20},
+		{100, 0, 100},
+		{0, 0, 0},
+		{100, -50, 100},
+		{0, -50, 0},
+	}
+
+	for _, tt := range tests {
+		if got := percentUsed(tt.spent, tt.available); got != tt.want {
+			t.Errorf("percentUsed(%d, %d) = %v, want %v", tt.spent, tt.available, got, tt.want)
+		}
+	}
+}
+
+func TestRollup(t *testing.T) {
+	id := func(n uint) *uint { return &n }
+
+	tests := []struct {
+		name    string
+		spent   map[uint]int64
+		parents map[uint]*uint
+		want    map[uint]int64
+	}{
+		{
+			name:  "top-level categories",
+			spent: map[uint]int64{1: 100, 2: 50},
+			want:  map[uint]int64{1: 100, 2: 50},
+		},
+		{
+			name:    "spending adds up to every ancestor",
+			spent:   map[uint]int64{3: 100, 2: 50},
+			parents: map[uint]*uint{2: id(1), 3: id(2)},
+			want:    map[uint]int64{1: 150, 2: 150, 3: 100},
+		},
+		{
+			name:    "a cycle is walked once",
+			spent:   map[uint]int64{1: 10},
+			parents: map[uint]*uint{1: id(2), 2: id(1)},
+			want:    map[uint]int64{1: 10, 2: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Rollup(tt.spent, tt.parents)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Rollup() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("Rollup()[%d] = %d, want %d", k, got[k], v)
+				}
+			}
+		})
+	}
+}
@@ -27,8 +27,50 @@ func New(addr string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*gorm
 		return nil, err
 	}
 
+	// Tag names are case-insensitive
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS citext").Error; err != nil {
+		return nil, err
+	}
+
+	// Users that existed before email activation was introduced have
+	// already been using their accounts, so they start out activated.
+	backfillActivation := !db.Migrator().HasColumn(&model.User{}, "is_active")
+
 	// Auto migrate the schema
-	db.AutoMigrate(&model.User{})
+	err = db.AutoMigrate(
+		&model.User{},
+		&model.UserInvitation{},
+		&model.PasswordReset{},
+		&model.RecoveryCode{},
+		&model.MFAChallenge{},
+		&model.Session{},
+		&model.RefreshToken{},
+		&model.Account{},
+		&model.Category{},
+		&model.Tag{},
+		&model.Transaction{},
+		&model.TransactionSplit{},
+		&model.Attachment{},
+		&model.Budget{},
+		&model.ImportBatch{},
+		&model.DuplicateDismissal{},
+		&model.Rule{},
+		&model.RecurringTransaction{},
+		&model.ExchangeRate{},
+		&model.NetWorthSnapshot{},
+		&model.Goal{},
+		&model.GoalContribution{},
+		&model.Debt{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if backfillActivation {
+		if err := db.Exec("UPDATE users SET is_active = true").Error; err != nil {
+			return nil, err
+		}
+	}
 
 	return db, nil
 }
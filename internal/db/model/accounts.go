@@ -0,0 +1,35 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type AccountType string
+
+const (
+	AccountTypeChecking   AccountType = "checking"
+	AccountTypeSavings    AccountType = "savings"
+	AccountTypeCreditCard AccountType = "credit_card"
+	AccountTypeCash       AccountType = "cash"
+	AccountTypeLoan       AccountType = "loan"
+	AccountTypeInvestment AccountType = "investment"
+)
+
+// Account model for database
+//
+// Amounts are stored in the currency's minor units (e.g. cents). Balance is
+// derived from OpeningBalance plus every transaction posted to the account and
+// is only ever changed by the store, never directly by a handler.
+// LowBalanceThreshold, when set, is the balance below which the cash-flow
+// forecast warns the user.
+type Account struct {
+	gorm.Model
+	UserID         uint        `gorm:"not null;index" json:"user_id"`
+	Name           string      `gorm:"not null" json:"name"`
+	Type           AccountType `gorm:"type:varchar(20);not null" json:"type"`
+	Currency       string      `gorm:"type:char(3);not null" json:"currency"`
+	OpeningBalance int64       `gorm:"not null;default:0" json:"opening_balance"`
+	Balance        int64       `gorm:"not null;default:0" json:"balance"`
+
+	LowBalanceThreshold *int64 `json:"low_balance_threshold"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Attachment model for database
+//
+// It is a file, such as a receipt, uploaded for a transaction. The content
+// lives in blob storage under Key; ContentType is sniffed from the content
+// rather than taken from the upload, and Size counts towards the user's
+// storage quota.
+type Attachment struct {
+	gorm.Model
+	UserID        uint   `gorm:"not null;index" json:"user_id"`
+	TransactionID uint   `gorm:"not null;index" json:"transaction_id"`
+	Filename      string `gorm:"not null" json:"filename"`
+	ContentType   string `gorm:"not null" json:"content_type"`
+	Size          int64  `gorm:"not null" json:"size"`
+	Key           string `gorm:"not null;uniqueIndex" json:"-"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Budget model for database
+//
+// A budget caps spending in one category for one calendar month. Month is
+// always the first day of that month. When Rollover is set, whatever was left
+// (or overspent) in the previous month's budget for the same category is
+// carried into this one. Amount is in the user's home currency and spending in
+// other currencies is converted before it is compared against it.
+type Budget struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_budgets_user_category_month,where:deleted_at IS NULL" json:"user_id"`
+	CategoryID uint      `gorm:"not null;uniqueIndex:idx_budgets_user_category_month,where:deleted_at IS NULL" json:"category_id"`
+	Month      time.Time `gorm:"type:date;not null;uniqueIndex:idx_budgets_user_category_month,where:deleted_at IS NULL" json:"month"`
+	Amount     int64     `gorm:"not null" json:"amount"`
+	Rollover   bool      `gorm:"not null;default:false" json:"rollover"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type CategoryKind string
+
+const (
+	CategoryKindIncome  CategoryKind = "income"
+	CategoryKindExpense CategoryKind = "expense"
+)
+
+// Category model for database
+//
+// Categories form a tree per user through ParentID, e.g. Food > Groceries. A
+// child always has the same kind as its parent.
+type Category struct {
+	gorm.Model
+	UserID   uint         `gorm:"not null;index" json:"user_id"`
+	ParentID *uint        `gorm:"index" json:"parent_id"`
+	Name     string       `gorm:"not null" json:"name"`
+	Kind     CategoryKind `gorm:"type:varchar(10);not null" json:"kind"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Debt model for database
+//
+// It holds the repayment terms of a loan or credit card account. What is owed
+// is the account's balance, which is negative while money is owed. APR is in
+// basis points, so 1999 is 19.99%.
+type Debt struct {
+	gorm.Model
+	UserID         uint  `gorm:"not null;index" json:"user_id"`
+	AccountID      uint  `gorm:"not null;uniqueIndex:idx_debts_account,where:deleted_at IS NULL" json:"account_id"`
+	APR            int   `gorm:"column:apr;not null" json:"apr"`
+	MinimumPayment int64 `gorm:"not null" json:"minimum_payment"`
+}
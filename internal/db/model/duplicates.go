@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// DuplicateDismissal model for database
+//
+// It records that the user looked at a suspected duplicate pair and decided
+// the two transactions are distinct, so the pair is not flagged again. The
+// lower transaction id is always stored in TransactionAID.
+type DuplicateDismissal struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	UserID         uint      `gorm:"not null;index" json:"user_id"`
+	TransactionAID uint      `gorm:"not null;uniqueIndex:idx_duplicate_dismissals_pair,priority:1" json:"transaction_a_id"`
+	TransactionBID uint      `gorm:"not null;uniqueIndex:idx_duplicate_dismissals_pair,priority:2" json:"transaction_b_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Goal model for database
+//
+// A goal is saving TargetAmount in the currency of AccountID, optionally by
+// TargetDate. Normally the whole account balance counts towards it; when
+// Earmarked is set only the GoalContributions recorded against the goal do,
+// so several goals can share one account. Status is the last evaluated
+// status and is only used to notice changes.
+type Goal struct {
+	gorm.Model
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	AccountID    uint       `gorm:"not null;index" json:"account_id"`
+	Name         string     `gorm:"not null" json:"name"`
+	TargetAmount int64      `gorm:"not null" json:"target_amount"`
+	TargetDate   *time.Time `gorm:"type:date" json:"target_date"`
+	Earmarked    bool       `gorm:"not null;default:false" json:"earmarked"`
+	Status       string     `gorm:"type:varchar(20);not null;default:''" json:"-"`
+}
+
+// GoalContribution model for database
+//
+// It puts Amount of an account's balance aside for an earmarked goal. A
+// negative amount takes money back out.
+type GoalContribution struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	GoalID    uint      `gorm:"not null;index" json:"goal_id"`
+	Amount    int64     `gorm:"not null" json:"amount"`
+	Date      time.Time `gorm:"type:date;not null" json:"date"`
+	Memo      string    `gorm:"not null;default:''" json:"memo"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type ImportSource string
+
+const (
+	ImportSourceCSV ImportSource = "csv"
+	ImportSourceOFX ImportSource = "ofx"
+)
+
+// ImportBatch model for database
+//
+// Every transaction created by a statement import points back at its batch
+// so the whole import can be undone in one go.
+type ImportBatch struct {
+	gorm.Model
+	UserID    uint         `gorm:"not null;index" json:"user_id"`
+	AccountID uint         `gorm:"not null;index" json:"account_id"`
+	Source    ImportSource `gorm:"type:varchar(10);not null" json:"source"`
+	Filename  string       `gorm:"not null;default:''" json:"filename"`
+	RowCount  int          `gorm:"not null;default:0" json:"row_count"`
+	// DuplicateCount is the number of rows skipped because a transaction
+	// with the same FITID already existed on the account.
+	DuplicateCount int `gorm:"not null;default:0" json:"duplicate_count"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// UserInvitation model for database
+//
+// It is the activation token emailed to a new user to confirm their address.
+// Only the SHA-256 hash of the token is stored. An invitation can be used
+// once, before ExpiresAt, and is replaced when a new one is sent.
+type UserInvitation struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"type:timestamp with time zone;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp with time zone;not null"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// RecoveryCode model for database
+//
+// Recovery codes stand in for an authenticator app that was lost. Only the
+// SHA-256 hash of a code is stored and each code can be used once.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;index"`
+	CodeHash  string     `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time `gorm:"type:timestamp with time zone"`
+	CreatedAt time.Time  `gorm:"type:timestamp with time zone;not null"`
+}
+
+// MFAChallenge model for database
+//
+// A challenge is handed out when a user with two-factor authentication gives
+// the right password, and is exchanged together with a second factor for the
+// access and refresh tokens. Only the SHA-256 hash of its token is stored,
+// and Attempts counts the codes tried against it.
+type MFAChallenge struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"type:timestamp with time zone;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp with time zone;not null"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// NetWorthSnapshot model for database
+//
+// It records an account's closing balance on Date, both in the account's own
+// currency and converted into the owner's home currency at the time.
+// HomeBalance is nil when no exchange rate was available.
+type NetWorthSnapshot struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	UserID       uint      `gorm:"not null;index:idx_net_worth_snapshots_user_date,priority:1" json:"user_id"`
+	AccountID    uint      `gorm:"not null;uniqueIndex:idx_net_worth_snapshots_account_date,priority:1" json:"account_id"`
+	Date         time.Time `gorm:"type:date;not null;index:idx_net_worth_snapshots_user_date,priority:2;uniqueIndex:idx_net_worth_snapshots_account_date,priority:2" json:"date"`
+	Currency     string    `gorm:"type:char(3);not null" json:"currency"`
+	Balance      int64     `gorm:"not null" json:"balance"`
+	HomeCurrency string    `gorm:"type:char(3);not null" json:"home_currency"`
+	HomeBalance  *int64    `json:"home_balance"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// PasswordReset model for database
+//
+// It is the token emailed to a user who forgot their password. Only the
+// SHA-256 hash of the token is stored. A reset can be used once, before
+// ExpiresAt, and is replaced when a new one is requested.
+type PasswordReset struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"type:timestamp with time zone;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp with time zone;not null"`
+}
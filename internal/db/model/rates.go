@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+// ExchangeRate model for database
+//
+// One unit of Base was worth Rate units of Quote on Date. Rates are uploaded
+// per user and Rate is kept as an exact decimal string, never a float.
+type ExchangeRate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_exchange_rates_pair_date,priority:1" json:"user_id"`
+	Base      string    `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rates_pair_date,priority:2" json:"base"`
+	Quote     string    `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rates_pair_date,priority:3" json:"quote"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex:idx_exchange_rates_pair_date,priority:4" json:"date"`
+	Rate      string    `gorm:"type:numeric;not null" json:"rate"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecurringTransaction model for database
+//
+// It is a template that the scheduler turns into a real Transaction on each
+// date produced by its schedule. NextDate is the earliest occurrence that has
+// not been posted or skipped yet; the scheduler only ever moves it forward.
+type RecurringTransaction struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index" json:"user_id"`
+	AccountID  uint   `gorm:"not null" json:"account_id"`
+	CategoryID *uint  `json:"category_id"`
+	Amount     int64  `gorm:"not null" json:"amount"`
+	Payee      string `gorm:"not null;default:''" json:"payee"`
+	Memo       string `gorm:"not null;default:''" json:"memo"`
+
+	Frequency       string     `gorm:"type:varchar(10);not null" json:"frequency"`
+	Interval        int        `gorm:"not null;default:1" json:"interval"`
+	ByMonthDay      int        `gorm:"not null;default:0" json:"by_month_day"`
+	LastBusinessDay bool       `gorm:"not null;default:false" json:"last_business_day"`
+	StartDate       time.Time  `gorm:"type:date;not null" json:"start_date"`
+	EndDate         *time.Time `gorm:"type:date" json:"end_date"`
+
+	NextDate     *time.Time `gorm:"type:date;index" json:"next_date"`
+	SkippedDates []string   `gorm:"serializer:json" json:"skipped_dates"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken model for database
+//
+// Only the SHA-256 hash of a refresh token is stored. Each login starts a new
+// session; every refresh marks the presented token as rotated and issues the
+// next token of the same session. Presenting a rotated token again means it
+// was stolen or replayed, so the whole session is revoked. UserAgent and IP
+// describe the device the token was issued to.
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;index"`
+	SessionID uint       `gorm:"not null;index"`
+	TokenHash string     `gorm:"type:char(64);not null;uniqueIndex"`
+	UserAgent string     `gorm:"not null;default:''"`
+	IP        string     `gorm:"not null;default:''"`
+	ExpiresAt time.Time  `gorm:"type:timestamp with time zone;not null"`
+	RotatedAt *time.Time `gorm:"type:timestamp with time zone"`
+	RevokedAt *time.Time `gorm:"type:timestamp with time zone"`
+	CreatedAt time.Time  `gorm:"type:timestamp with time zone;not null"`
+}
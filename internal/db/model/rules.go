@@ -0,0 +1,40 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type PayeeMatch string
+
+const (
+	PayeeMatchContains PayeeMatch = "contains"
+	PayeeMatchRegex    PayeeMatch = "regex"
+	PayeeMatchExact    PayeeMatch = "exact"
+)
+
+// Rule model for database
+//
+// A rule fires when every condition that is set matches a transaction; unset
+// conditions are ignored. Rules run in ascending Priority and the first rule
+// to set a field wins, except for tags which accumulate.
+type Rule struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Name     string `gorm:"not null" json:"name"`
+	Priority int    `gorm:"not null;default:0" json:"priority"`
+	Enabled  bool   `gorm:"not null;default:true" json:"enabled"`
+
+	// Conditions
+	PayeeMatch   PayeeMatch `gorm:"type:varchar(10);not null;default:''" json:"payee_match"`
+	PayeePattern string     `gorm:"not null;default:''" json:"payee_pattern"`
+	MinAmount    *int64     `json:"min_amount"`
+	MaxAmount    *int64     `json:"max_amount"`
+	AccountID    *uint      `json:"account_id"`
+	MemoContains string     `gorm:"not null;default:''" json:"memo_contains"`
+
+	// Actions
+	SetCategoryID *uint    `json:"set_category_id"`
+	RenamePayee   string   `gorm:"not null;default:''" json:"rename_payee"`
+	AddTags       []string `gorm:"serializer:json" json:"add_tags"`
+	MarkTransfer  bool     `gorm:"not null;default:false" json:"mark_transfer"`
+}
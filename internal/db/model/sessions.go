@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// Session model for database
+//
+// A session is started by every login and lasts as long as its refresh
+// tokens keep being rotated. UserAgent and IP are those of the last refresh,
+// and ExpiresAt is when its newest refresh token expires. Revoking a session
+// revokes its refresh tokens and the access tokens issued for it.
+type Session struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	UserAgent  string     `gorm:"not null;default:''" json:"user_agent"`
+	IP         string     `gorm:"not null;default:''" json:"ip"`
+	ExpiresAt  time.Time  `gorm:"type:timestamp with time zone;not null" json:"expires_at"`
+	LastUsedAt time.Time  `gorm:"type:timestamp with time zone;not null" json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"type:timestamp with time zone" json:"-"`
+	CreatedAt  time.Time  `gorm:"type:timestamp with time zone;not null" json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Tag model for database
+//
+// Name is citext, so each user has at most one live tag per name regardless
+// of case, the same way users.email is unique.
+type Tag struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index;uniqueIndex:idx_tags_user_name,where:deleted_at IS NULL" json:"user_id"`
+	Name   string `gorm:"type:citext;not null;uniqueIndex:idx_tags_user_name,where:deleted_at IS NULL" json:"name"`
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Transaction model for database
+//
+// Amount is in the minor units of the account's currency; money leaving the
+// account is negative and money coming in is positive.
+//
+// A transfer between two of the user's own accounts is stored as two legs
+// that point at each other through TransferPeerID; both are flagged with
+// IsTransfer so they are left out of income and expense figures.
+//
+// FITID is the bank's own identifier for a transaction imported from an OFX
+// statement. It is unique per account so overlapping statements can be
+// re-imported without creating duplicates.
+//
+// A transaction can be split across several categories. IsSplit is then set,
+// CategoryID is left empty and the Splits, which always add up to Amount,
+// carry the categories instead.
+type Transaction struct {
+	gorm.Model
+	UserID         uint      `gorm:"not null;index:idx_transactions_user_date,priority:1" json:"user_id"`
+	AccountID      uint      `gorm:"not null;index;index:idx_transactions_account_amount,priority:1;uniqueIndex:idx_transactions_account_fitid,where:fit_id <> '' AND deleted_at IS NULL" json:"account_id"`
+	CategoryID     *uint     `gorm:"index" json:"category_id"`
+	Amount         int64     `gorm:"not null;index:idx_transactions_account_amount,priority:2" json:"amount"`
+	Date           time.Time `gorm:"type:date;not null;index:idx_transactions_user_date,priority:2" json:"date"`
+	Payee          string    `gorm:"not null;default:''" json:"payee"`
+	Memo           string    `gorm:"not null;default:''" json:"memo"`
+	IsTransfer     bool      `gorm:"not null;default:false" json:"is_transfer"`
+	TransferPeerID *uint     `gorm:"index" json:"transfer_peer_id"`
+	ImportBatchID  *uint     `gorm:"index" json:"import_batch_id"`
+	FITID          string    `gorm:"column:fit_id;not null;default:'';uniqueIndex:idx_transactions_account_fitid,where:fit_id <> '' AND deleted_at IS NULL" json:"fitid,omitempty"`
+	Tags           []Tag     `gorm:"many2many:transaction_tags" json:"tags,omitempty"`
+
+	// RecurringID and OccurrenceDate identify the scheduled occurrence a
+	// transaction was posted for; each occurrence can be posted only once.
+	RecurringID    *uint      `gorm:"uniqueIndex:idx_transactions_recurring_occurrence,where:recurring_id IS NOT NULL" json:"recurring_id,omitempty"`
+	OccurrenceDate *time.Time `gorm:"type:date;uniqueIndex:idx_transactions_recurring_occurrence,where:recurring_id IS NOT NULL" json:"occurrence_date,omitempty"`
+
+	IsSplit bool               `gorm:"not null;default:false" json:"is_split"`
+	Splits  []TransactionSplit `json:"splits,omitempty"`
+}
+
+// TransactionSplit model for database
+//
+// It is one line of a split transaction, in the currency of the
+// transaction's account and with the same sign convention.
+type TransactionSplit struct {
+	ID            uint   `gorm:"primarykey" json:"id"`
+	TransactionID uint   `gorm:"not null;index" json:"transaction_id"`
+	CategoryID    *uint  `gorm:"index" json:"category_id"`
+	Amount        int64  `gorm:"not null" json:"amount"`
+	Memo          string `gorm:"not null;default:''" json:"memo"`
+}
@@ -15,4 +15,20 @@ type User struct {
 	Password  string    `gorm:"not null" json:"-"`
 	CreatedAt time.Time `gorm:"type:timestamp with time zone;not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt time.Time `gorm:"type:timestamp with time zone;not null;default:CURRENT_TIMESTAMP"`
+
+	// HomeCurrency is the ISO 4217 code reports and the dashboard convert
+	// every amount into.
+	HomeCurrency string `gorm:"type:char(3);not null;default:'USD'" json:"home_currency"`
+
+	// IsActive is set once the user has confirmed their email address;
+	// until then they cannot log in.
+	IsActive bool `gorm:"not null;default:false" json:"is_active"`
+
+	// TOTPSecret is the base32 secret shared with the user's authenticator
+	// app. It is set when enrolment starts but only asked for at login once
+	// a first code has confirmed it and TOTPEnabled is set. TOTPLastStep is
+	// the time step of the last code accepted, so no code works twice.
+	TOTPSecret   string `gorm:"not null;default:''" json:"-"`
+	TOTPEnabled  bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastStep int64  `gorm:"not null;default:0" json:"-"`
 }
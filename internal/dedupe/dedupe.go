@@ -0,0 +1,141 @@
+// Package dedupe scores pairs of transactions on how likely they are to be
+// the same real-world payment entered twice, e.g. once by hand and once from
+// a CSV import.
+package dedupe
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+const (
+	DefaultWindowDays = 3
+	DefaultMinScore   = 0.6
+
+	dateWeight  = 0.4
+	payeeWeight = 0.6
+
+	// minPrefix is how many letters a payee needs before it counts as a
+	// truncated form of a longer one.
+	minPrefix = 4
+)
+
+// Score returns a value between 0 and 1 for a pair of transactions. Pairs on
+// different accounts, with different amounts, further apart than windowDays
+// or carrying two different bank FITIDs always score 0. Otherwise the score
+// blends how close the dates are with how similar the payees are.
+func Score(a, b model.Transaction, windowDays int) float64 {
+	if a.AccountID != b.AccountID || a.Amount != b.Amount {
+		return 0
+	}
+	if a.FITID != "" && b.FITID != "" && a.FITID != b.FITID {
+		return 0
+	}
+
+	days := math.Abs(a.Date.Sub(b.Date).Hours() / 24)
+	if days > float64(windowDays) {
+		return 0
+	}
+
+	dateScore := 1 - days/float64(windowDays+1)
+
+	return round(dateWeight*dateScore + payeeWeight*PayeeSimilarity(a.Payee, b.Payee))
+}
+
+// PayeeSimilarity compares two payee strings after normalising them, taking
+// the better of an edit-distance ratio and the share of common words so that
+// "AMZN Mktp US*2K4" and "Amazon Marketplace" are not scored against each
+// other on reference numbers. A payee that is a prefix of the other, as banks
+// truncate long descriptions, scores at least halfway, more the more of the
+// longer payee it covers. Two empty payees are treated as neutral.
+func PayeeSimilarity(a, b string) float64 {
+	a, b = normalize(a), normalize(b)
+	if a == "" && b == "" {
+		return 0.5
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := float64(max(len(ra), len(rb)))
+	edit := 1 - float64(levenshtein(ra, rb))/longest
+
+	var prefix float64
+	if shortest := min(len(ra), len(rb)); shortest >= minPrefix &&
+		(strings.HasPrefix(a, b) || strings.HasPrefix(b, a)) {
+		prefix = 0.5 + 0.5*float64(shortest)/longest
+	}
+
+	return max(edit, tokenOverlap(a, b), prefix)
+}
+
+// normalize lowercases s and drops digits and punctuation, which in bank
+// descriptions are mostly card numbers, dates and reference codes.
+func normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r):
+			if space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+func tokenOverlap(a, b string) float64 {
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	set := make(map[string]bool, len(ta))
+	for _, t := range ta {
+		set[t] = true
+	}
+
+	common := 0
+	for _, t := range tb {
+		if set[t] {
+			common++
+			delete(set, t)
+		}
+	}
+
+	return float64(common) / float64(max(len(ta), len(tb)))
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
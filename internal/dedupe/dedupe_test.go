@@ -0,0 +1,66 @@
+package dedupe
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+func TestPayeeSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 0.5},
+		{"Amazon", "", 0},
+		{"AMAZON.COM", "amazon com", 1},
+		{"UBER *TRIP 1234", "Uber trip", 1},
+		{"Whole Foods Mark", "Whole Foods Market", 0.944},
+		{"Amazon", "Amazon Marketplace", 0.667},
+		{"Amazon Marketplace", "Amazon", 0.667},
+		{"marketplace amazon", "amazon marketplace", 1},
+		{"A", "Amazon", 0.167},
+		{"Amz", "Amazon", 0.5},
+		{"Starbucks", "Shell", 0.111},
+	}
+
+	for _, tt := range tests {
+		if got := PayeeSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("PayeeSimilarity(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	day := time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)
+	base := model.Transaction{AccountID: 1, Amount: -4250, Date: day, Payee: "Whole Foods Market"}
+
+	tests := []struct {
+		name   string
+		modify func(tx *model.Transaction)
+		want   float64
+	}{
+		{"same payment", func(tx *model.Transaction) {}, 1},
+		{"a day apart", func(tx *model.Transaction) { tx.Date = day.AddDate(0, 0, 1) }, 0.9},
+		{"truncated payee", func(tx *model.Transaction) { tx.Payee = "WHOLE FOODS MARK #123" }, 0.967},
+		{"single letter payee", func(tx *model.Transaction) { tx.Payee = "W" }, 0.433},
+		{"other account", func(tx *model.Transaction) { tx.AccountID = 2 }, 0},
+		{"other amount", func(tx *model.Transaction) { tx.Amount = -4251 }, 0},
+		{"outside the window", func(tx *model.Transaction) { tx.Date = day.AddDate(0, 0, -4) }, 0},
+		{"different bank ids", func(tx *model.Transaction) { tx.FITID = "B" }, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := base, base
+			a.FITID = "A"
+			tt.modify(&b)
+
+			if got := Score(a, b, DefaultWindowDays); got != tt.want {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
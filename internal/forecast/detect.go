@@ -0,0 +1,197 @@
+package forecast
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/schedule"
+)
+
+// minOccurrences is how many times a payee has to show up at a steady
+// cadence before it is treated as repeating.
+const minOccurrences = 3
+
+// cadence is a repeat interval a payee can be detected at. Every gap between
+// consecutive transactions has to fall within [minGap, maxGap] days, and a
+// series whose next date is more than grace days overdue is assumed to have
+// stopped.
+type cadence struct {
+	frequency schedule.Frequency
+	interval  int
+	minGap    int
+	maxGap    int
+	grace     int
+}
+
+var cadences = []cadence{
+	{frequency: schedule.Weekly, interval: 1, minGap: 6, maxGap: 8, grace: 3},
+	{frequency: schedule.Weekly, interval: 2, minGap: 12, maxGap: 16, grace: 4},
+	{frequency: schedule.Monthly, interval: 1, minGap: 26, maxGap: 35, grace: 7},
+	{frequency: schedule.Monthly, interval: 3, minGap: 85, maxGap: 97, grace: 14},
+}
+
+// Series is a payee detected as repeating on one account.
+type Series struct {
+	AccountID  uint          `json:"account_id"`
+	CategoryID *uint         `json:"category_id"`
+	Payee      string        `json:"payee"`
+	Amount     int64         `json:"amount"`
+	Rule       schedule.Rule `json:"-"`
+	Last       time.Time     `json:"last"`
+	Count      int           `json:"count"`
+
+	grace int
+}
+
+// Detect finds payees in history that repeat at a steady weekly, fortnightly,
+// monthly or quarterly cadence with amounts of the same sign, and that are
+// still active as of start. The expected amount is the median of past
+// amounts. Payees already covered by a declared event on the same account
+// are skipped so they are not counted twice.
+func Detect(history []Transaction, start time.Time, declared []Event) []Series {
+	skip := map[payeeKey]bool{}
+	for _, e := range declared {
+		skip[keyOf(e.AccountID, e.Payee)] = true
+	}
+
+	groups := map[payeeKey][]Transaction{}
+	var keys []payeeKey
+	for _, t := range history {
+		k := keyOf(t.AccountID, t.Payee)
+		if k.payee == "" || skip[k] {
+			continue
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], t)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].accountID != keys[j].accountID {
+			return keys[i].accountID < keys[j].accountID
+		}
+		return keys[i].payee < keys[j].payee
+	})
+
+	var series []Series
+	for _, k := range keys {
+		if s, ok := detectSeries(groups[k], start); ok {
+			series = append(series, s)
+		}
+	}
+
+	return series
+}
+
+func detectSeries(txns []Transaction, start time.Time) (Series, bool) {
+	if len(txns) < minOccurrences {
+		return Series{}, false
+	}
+
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].Date.Before(txns[j].Date) })
+
+	amounts := make([]int64, len(txns))
+	for i, t := range txns {
+		if (t.Amount < 0) != (txns[0].Amount < 0) || t.Amount == 0 {
+			return Series{}, false
+		}
+		amounts[i] = t.Amount
+	}
+
+	c, ok := matchCadence(txns)
+	if !ok {
+		return Series{}, false
+	}
+
+	last := txns[len(txns)-1]
+	rule := schedule.Rule{
+		Frequency: c.frequency,
+		Interval:  c.interval,
+		Start:     last.Date,
+	}
+
+	next, ok := rule.Next(last.Date.AddDate(0, 0, 1))
+	if !ok || next.AddDate(0, 0, c.grace).Before(start) {
+		return Series{}, false
+	}
+
+	return Series{
+		AccountID:  last.AccountID,
+		CategoryID: last.CategoryID,
+		Payee:      last.Payee,
+		Amount:     median(amounts),
+		Rule:       rule,
+		Last:       last.Date,
+		Count:      len(txns),
+		grace:      c.grace,
+	}, true
+}
+
+func matchCadence(txns []Transaction) (cadence, bool) {
+	for _, c := range cadences {
+		steady := true
+		for i := 1; i < len(txns); i++ {
+			gap := int(txns[i].Date.Sub(txns[i-1].Date).Hours() / 24)
+			if gap < c.minGap || gap > c.maxGap {
+				steady = false
+				break
+			}
+		}
+		if steady {
+			return c, true
+		}
+	}
+
+	return cadence{}, false
+}
+
+// events expands s into dated events in [start, end]. An occurrence that is
+// overdue but still within its grace period is expected on start.
+func (s Series) events(start, end time.Time) []Event {
+	var events []Event
+	for _, d := range s.Rule.Between(s.Last.AddDate(0, 0, 1), end) {
+		if d.Before(start) {
+			if d.AddDate(0, 0, s.grace).Before(start) {
+				continue
+			}
+			d = start
+		}
+		events = append(events, Event{
+			AccountID:  s.AccountID,
+			CategoryID: s.CategoryID,
+			Date:       d,
+			Amount:     s.Amount,
+			Payee:      s.Payee,
+			Source:     SourceDetected,
+		})
+	}
+
+	return events
+}
+
+type payeeKey struct {
+	accountID uint
+	payee     string
+}
+
+// keyOf identifies a payee on an account regardless of case and spacing.
+func keyOf(accountID uint, payee string) payeeKey {
+	return payeeKey{
+		accountID: accountID,
+		payee:     strings.Join(strings.Fields(strings.ToLower(payee)), " "),
+	}
+}
+
+func median(values []int64) int64 {
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
@@ -0,0 +1,149 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/schedule"
+)
+
+// history returns one transaction per date for payee on account 1.
+func history(payee string, amount int64, dates ...string) []Transaction {
+	txns := make([]Transaction, len(dates))
+	for i, d := range dates {
+		txns[i] = Transaction{AccountID: 1, Date: date(d), Amount: amount, Payee: payee}
+	}
+	return txns
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []Transaction
+		start    string
+		declared []Event
+
+		// want is nil when nothing should be detected.
+		want *Series
+	}{
+		{
+			name:    "weekly",
+			history: history("Gym", -1500, "2026-03-01", "2026-03-08", "2026-03-15"),
+			start:   "2026-03-20",
+			want:    &Series{Payee: "Gym", Amount: -1500, Rule: schedule.Rule{Frequency: schedule.Weekly, Interval: 1}, Last: date("2026-03-15"), Count: 3},
+		},
+		{
+			name:    "fortnightly",
+			history: history("Payroll", 200000, "2026-02-06", "2026-02-20", "2026-03-06"),
+			start:   "2026-03-10",
+			want:    &Series{Payee: "Payroll", Amount: 200000, Rule: schedule.Rule{Frequency: schedule.Weekly, Interval: 2}, Last: date("2026-03-06"), Count: 3},
+		},
+		{
+			name:    "monthly",
+			history: history("Rent", -90000, "2026-01-01", "2026-01-31", "2026-03-02"),
+			start:   "2026-03-10",
+			want:    &Series{Payee: "Rent", Amount: -90000, Rule: schedule.Rule{Frequency: schedule.Monthly, Interval: 1}, Last: date("2026-03-02"), Count: 3},
+		},
+		{
+			name:    "quarterly",
+			history: history("Water", -4500, "2025-10-01", "2026-01-01", "2026-04-01"),
+			start:   "2026-05-01",
+			want:    &Series{Payee: "Water", Amount: -4500, Rule: schedule.Rule{Frequency: schedule.Monthly, Interval: 3}, Last: date("2026-04-01"), Count: 3},
+		},
+		{
+			name: "median amount and normalized payee",
+			history: []Transaction{
+				{AccountID: 1, Date: date("2026-01-05"), Amount: -1000, Payee: "Netflix"},
+				{AccountID: 1, Date: date("2026-02-05"), Amount: -1200, Payee: "NETFLIX "},
+				{AccountID: 1, Date: date("2026-03-05"), Amount: -1100, Payee: "netflix"},
+				{AccountID: 1, Date: date("2026-04-05"), Amount: -1300, Payee: "Netflix"},
+			},
+			start: "2026-04-10",
+			want:  &Series{Payee: "Netflix", Amount: -1150, Rule: schedule.Rule{Frequency: schedule.Monthly, Interval: 1}, Last: date("2026-04-05"), Count: 4},
+		},
+		{
+			name:    "overdue within the grace period",
+			history: history("Rent", -90000, "2026-01-05", "2026-02-05", "2026-03-05"),
+			start:   "2026-04-12",
+			want:    &Series{Payee: "Rent", Amount: -90000, Rule: schedule.Rule{Frequency: schedule.Monthly, Interval: 1}, Last: date("2026-03-05"), Count: 3},
+		},
+		{
+			name:    "overdue past the grace period",
+			history: history("Rent", -90000, "2026-01-05", "2026-02-05", "2026-03-05"),
+			start:   "2026-04-13",
+		},
+		{
+			name:    "too few occurrences",
+			history: history("Rent", -90000, "2026-02-05", "2026-03-05"),
+			start:   "2026-03-10",
+		},
+		{
+			name:    "irregular gaps",
+			history: history("Cafe", -450, "2026-03-01", "2026-03-08", "2026-03-28"),
+			start:   "2026-03-30",
+		},
+		{
+			name: "mixed signs",
+			history: []Transaction{
+				{AccountID: 1, Date: date("2026-01-05"), Amount: -1000, Payee: "Store"},
+				{AccountID: 1, Date: date("2026-02-05"), Amount: 1000, Payee: "Store"},
+				{AccountID: 1, Date: date("2026-03-05"), Amount: -1000, Payee: "Store"},
+			},
+			start: "2026-03-10",
+		},
+		{
+			name:     "covered by a declared item",
+			history:  history("Rent", -90000, "2026-01-05", "2026-02-05", "2026-03-05"),
+			start:    "2026-03-10",
+			declared: []Event{{AccountID: 1, Date: date("2026-04-05"), Amount: -90000, Payee: " rent"}},
+		},
+		{
+			name:     "declared item on another account",
+			history:  history("Rent", -90000, "2026-01-05", "2026-02-05", "2026-03-05"),
+			start:    "2026-03-10",
+			declared: []Event{{AccountID: 2, Date: date("2026-04-05"), Amount: -90000, Payee: "Rent"}},
+			want:     &Series{Payee: "Rent", Amount: -90000, Rule: schedule.Rule{Frequency: schedule.Monthly, Interval: 1}, Last: date("2026-03-05"), Count: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.history, date(tt.start), tt.declared)
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Errorf("Detect() = %+v, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("Detect() = %+v, want one series", got)
+			}
+			s := got[0]
+			if s.Payee != tt.want.Payee || s.Amount != tt.want.Amount || !s.Last.Equal(tt.want.Last) || s.Count != tt.want.Count {
+				t.Errorf("Detect() = %+v, want %+v", s, *tt.want)
+			}
+			if s.Rule.Frequency != tt.want.Rule.Frequency || s.Rule.Interval != tt.want.Rule.Interval {
+				t.Errorf("rule = %s every %d, want %s every %d",
+					s.Rule.Frequency, s.Rule.Interval, tt.want.Rule.Frequency, tt.want.Rule.Interval)
+			}
+		})
+	}
+}
+
+func TestSeriesEvents(t *testing.T) {
+	series := Detect(history("Rent", -90000, "2026-01-05", "2026-02-05", "2026-03-05"), date("2026-04-10"), nil)
+	if len(series) != 1 {
+		t.Fatalf("Detect() = %+v, want one series", series)
+	}
+
+	got := series[0].events(date("2026-04-10"), date("2026-05-31"))
+	want := []time.Time{date("2026-04-10"), date("2026-05-05")}
+	if len(got) != len(want) {
+		t.Fatalf("events() = %+v, want dates %v", got, want)
+	}
+	for i, e := range got {
+		if !e.Date.Equal(want[i]) || e.Amount != -90000 || e.Source != SourceDetected {
+			t.Errorf("event %d = %+v, want %v", i, e, want[i])
+		}
+	}
+}
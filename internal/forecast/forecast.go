@@ -0,0 +1,329 @@
+// Package forecast projects account balances forward from known future
+// income and expenses. It has no database dependencies: callers gather the
+// balances, declared recurring items, past transactions and budgets, and
+// Project turns them into a deterministic day-by-day projection.
+package forecast
+
+import (
+	"sort"
+	"time"
+)
+
+type Source string
+
+const (
+	SourceRecurring Source = "recurring"
+	SourceDetected  Source = "detected"
+	SourceBudget    Source = "budget"
+)
+
+type AlertKind string
+
+const (
+	AlertNegative       AlertKind = "negative"
+	AlertBelowThreshold AlertKind = "below_threshold"
+)
+
+// Account is an account's balance at the start of the forecast. Threshold is
+// the balance the user does not want to drop below, if any.
+type Account struct {
+	ID        uint
+	Balance   int64
+	Threshold *int64
+}
+
+// Event is a single expected transaction. Amount is negative for outflows.
+type Event struct {
+	AccountID  uint      `json:"account_id"`
+	CategoryID *uint     `json:"category_id"`
+	Date       time.Time `json:"date"`
+	Amount     int64     `json:"amount"`
+	Payee      string    `json:"payee"`
+	Source     Source    `json:"source"`
+}
+
+// Transaction is a past transaction used to detect repeating payees.
+type Transaction struct {
+	AccountID  uint
+	CategoryID *uint
+	Date       time.Time
+	Amount     int64
+	Payee      string
+}
+
+// Budget is a monthly spending allowance for a category, expected to be spent
+// from AccountID. Monthly applies to future months and Remaining to the month
+// containing the start of the forecast. Both are in the account's currency.
+type Budget struct {
+	CategoryID uint
+	AccountID  uint
+	Monthly    int64
+	Remaining  int64
+}
+
+// Input is everything a forecast is computed from. Start is the first day
+// projected; Events are the declared occurrences from Start onwards.
+type Input struct {
+	Start    time.Time
+	Days     int
+	Accounts []Account
+	Events   []Event
+	History  []Transaction
+	Budgets  []Budget
+}
+
+// Point is an account's projected balance at the end of Date.
+type Point struct {
+	Date    time.Time `json:"date"`
+	Balance int64     `json:"balance"`
+}
+
+// Alert is a run of consecutive days on which an account is projected to be
+// negative or below its threshold. A negative run is reported as negative
+// only, even when the account also has a threshold.
+type Alert struct {
+	Kind   AlertKind `json:"kind"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Lowest Point     `json:"lowest"`
+}
+
+type Projection struct {
+	AccountID       uint    `json:"account_id"`
+	StartingBalance int64   `json:"starting_balance"`
+	Threshold       *int64  `json:"threshold"`
+	Points          []Point `json:"points"`
+	Lowest          Point   `json:"lowest"`
+	Alerts          []Alert `json:"alerts"`
+}
+
+// BudgetSpend is how much discretionary spending a budget adds to the
+// forecast on top of the known events in its category.
+type BudgetSpend struct {
+	CategoryID uint  `json:"category_id"`
+	AccountID  uint  `json:"account_id"`
+	Amount     int64 `json:"amount"`
+}
+
+type Forecast struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Events   []Event       `json:"events"`
+	Detected []Series      `json:"detected"`
+	Budgets  []BudgetSpend `json:"budgets"`
+	Accounts []Projection  `json:"accounts"`
+}
+
+// Project computes the forecast for in. Declared events are combined with
+// repeating payees detected in the history and with budgeted spending not
+// already covered by those events, which is spread evenly over the remaining
+// days of each month. Events for unknown accounts and outside the forecast
+// range are ignored.
+func Project(in Input) Forecast {
+	start := day(in.Start)
+	end := start.AddDate(0, 0, in.Days-1)
+
+	f := Forecast{
+		Start:    start,
+		End:      end,
+		Events:   []Event{},
+		Detected: []Series{},
+		Budgets:  []BudgetSpend{},
+		Accounts: []Projection{},
+	}
+
+	known := map[uint]bool{}
+	for _, a := range in.Accounts {
+		known[a.ID] = true
+	}
+	inRange := func(e Event) bool {
+		return known[e.AccountID] && !e.Date.Before(start) && !e.Date.After(end)
+	}
+
+	for _, e := range in.Events {
+		e.Date = day(e.Date)
+		if inRange(e) {
+			f.Events = append(f.Events, e)
+		}
+	}
+
+	for _, s := range Detect(in.History, start, in.Events) {
+		if !known[s.AccountID] {
+			continue
+		}
+		f.Detected = append(f.Detected, s)
+		for _, e := range s.events(start, end) {
+			if inRange(e) {
+				f.Events = append(f.Events, e)
+			}
+		}
+	}
+
+	sort.SliceStable(f.Events, func(i, j int) bool {
+		if !f.Events[i].Date.Equal(f.Events[j].Date) {
+			return f.Events[i].Date.Before(f.Events[j].Date)
+		}
+		return f.Events[i].AccountID < f.Events[j].AccountID
+	})
+
+	spending := budgetEvents(in.Budgets, f.Events, start, end)
+
+	deltas := map[uint]map[time.Time]int64{}
+	addDelta := func(e Event) {
+		if deltas[e.AccountID] == nil {
+			deltas[e.AccountID] = map[time.Time]int64{}
+		}
+		deltas[e.AccountID][e.Date] += e.Amount
+	}
+	for _, e := range f.Events {
+		addDelta(e)
+	}
+
+	spent := map[[2]uint]int64{}
+	var order [][2]uint
+	for _, e := range spending {
+		if !known[e.AccountID] {
+			continue
+		}
+		addDelta(e)
+
+		k := [2]uint{*e.CategoryID, e.AccountID}
+		if _, ok := spent[k]; !ok {
+			order = append(order, k)
+		}
+		spent[k] -= e.Amount
+	}
+	for _, k := range order {
+		f.Budgets = append(f.Budgets, BudgetSpend{CategoryID: k[0], AccountID: k[1], Amount: spent[k]})
+	}
+
+	for _, a := range in.Accounts {
+		f.Accounts = append(f.Accounts, project(a, deltas[a.ID], start, in.Days))
+	}
+
+	return f
+}
+
+func project(a Account, deltas map[time.Time]int64, start time.Time, days int) Projection {
+	p := Projection{
+		AccountID:       a.ID,
+		StartingBalance: a.Balance,
+		Threshold:       a.Threshold,
+		Points:          make([]Point, 0, days),
+		Alerts:          []Alert{},
+	}
+
+	balance := a.Balance
+	var current *Alert
+	for i := 0; i < days; i++ {
+		d := start.AddDate(0, 0, i)
+		balance += deltas[d]
+		point := Point{Date: d, Balance: balance}
+		p.Points = append(p.Points, point)
+
+		if i == 0 || balance < p.Lowest.Balance {
+			p.Lowest = point
+		}
+
+		kind, alerting := alertKind(balance, a.Threshold)
+		if current != nil && (!alerting || kind != current.Kind) {
+			p.Alerts = append(p.Alerts, *current)
+			current = nil
+		}
+		if !alerting {
+			continue
+		}
+		if current == nil {
+			current = &Alert{Kind: kind, From: d, Lowest: point}
+		}
+		current.To = d
+		if balance < current.Lowest.Balance {
+			current.Lowest = point
+		}
+	}
+	if current != nil {
+		p.Alerts = append(p.Alerts, *current)
+	}
+
+	return p
+}
+
+func alertKind(balance int64, threshold *int64) (AlertKind, bool) {
+	switch {
+	case balance < 0:
+		return AlertNegative, true
+	case threshold != nil && balance < *threshold:
+		return AlertBelowThreshold, true
+	default:
+		return "", false
+	}
+}
+
+// budgetEvents spreads, for every month touched by [start, end], what is left
+// of each budget after the known expenses in its category over the days of
+// that month from start onwards. Any remainder of the integer division is
+// spent on the earliest days, so the total always matches.
+func budgetEvents(budgets []Budget, known []Event, start, end time.Time) []Event {
+	var events []Event
+
+	for month := monthStart(start); !month.After(end); month = month.AddDate(0, 1, 0) {
+		first := month
+		if first.Before(start) {
+			first = start
+		}
+		last := month.AddDate(0, 1, -1)
+		days := int(last.Sub(first).Hours()/24) + 1
+
+		for _, b := range budgets {
+			allowance := b.Monthly
+			if month.Equal(monthStart(start)) {
+				allowance = b.Remaining
+			}
+
+			for _, e := range known {
+				if e.Amount < 0 && e.CategoryID != nil && *e.CategoryID == b.CategoryID &&
+					monthStart(e.Date).Equal(month) {
+					allowance += e.Amount
+				}
+			}
+			if allowance <= 0 {
+				continue
+			}
+
+			categoryID := b.CategoryID
+			per, extra := allowance/int64(days), allowance%int64(days)
+			for i := 0; i < days; i++ {
+				d := first.AddDate(0, 0, i)
+				if d.After(end) {
+					break
+				}
+
+				amount := per
+				if int64(i) < extra {
+					amount++
+				}
+				if amount == 0 {
+					continue
+				}
+
+				events = append(events, Event{
+					AccountID:  b.AccountID,
+					CategoryID: &categoryID,
+					Date:       d,
+					Amount:     -amount,
+					Source:     SourceBudget,
+				})
+			}
+		}
+	}
+
+	return events
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,222 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func amount(n int64) *int64 { return &n }
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		account Account
+		events  []Event
+
+		final  int64
+		lowest Point
+		alerts []Alert
+	}{
+		{
+			name:    "stays positive",
+			account: Account{ID: 1, Balance: 1000},
+			events:  []Event{{AccountID: 1, Date: date("2026-06-03"), Amount: -400}},
+			final:   600,
+			lowest:  Point{Date: date("2026-06-03"), Balance: 600},
+			alerts:  []Alert{},
+		},
+		{
+			name:    "negative run",
+			account: Account{ID: 1, Balance: 100},
+			events: []Event{
+				{AccountID: 1, Date: date("2026-06-02"), Amount: -300},
+				{AccountID: 1, Date: date("2026-06-03"), Amount: -100},
+				{AccountID: 1, Date: date("2026-06-05"), Amount: 1000},
+			},
+			final:  700,
+			lowest: Point{Date: date("2026-06-03"), Balance: -300},
+			alerts: []Alert{
+				{Kind: AlertNegative, From: date("2026-06-02"), To: date("2026-06-04"), Lowest: Point{Date: date("2026-06-03"), Balance: -300}},
+			},
+		},
+		{
+			name:    "below threshold then negative",
+			account: Account{ID: 1, Balance: 1000, Threshold: amount(500)},
+			events: []Event{
+				{AccountID: 1, Date: date("2026-06-02"), Amount: -600},
+				{AccountID: 1, Date: date("2026-06-04"), Amount: -500},
+				{AccountID: 1, Date: date("2026-06-06"), Amount: 1000},
+			},
+			final:  900,
+			lowest: Point{Date: date("2026-06-04"), Balance: -100},
+			alerts: []Alert{
+				{Kind: AlertBelowThreshold, From: date("2026-06-02"), To: date("2026-06-03"), Lowest: Point{Date: date("2026-06-02"), Balance: 400}},
+				{Kind: AlertNegative, From: date("2026-06-04"), To: date("2026-06-05"), Lowest: Point{Date: date("2026-06-04"), Balance: -100}},
+			},
+		},
+		{
+			name:    "run still open at the end",
+			account: Account{ID: 1, Balance: 1000, Threshold: amount(500)},
+			events: []Event{
+				{AccountID: 1, Date: date("2026-06-06"), Amount: -700},
+				{AccountID: 1, Date: date("2026-06-07"), Amount: 100},
+			},
+			final:  400,
+			lowest: Point{Date: date("2026-06-06"), Balance: 300},
+			alerts: []Alert{
+				{Kind: AlertBelowThreshold, From: date("2026-06-06"), To: date("2026-06-07"), Lowest: Point{Date: date("2026-06-06"), Balance: 300}},
+			},
+		},
+		{
+			name:    "events outside the range or for other accounts are ignored",
+			account: Account{ID: 1, Balance: 1000},
+			events: []Event{
+				{AccountID: 1, Date: date("2026-05-31"), Amount: -100},
+				{AccountID: 1, Date: date("2026-06-08"), Amount: -100},
+				{AccountID: 2, Date: date("2026-06-02"), Amount: -100},
+			},
+			final:  1000,
+			lowest: Point{Date: date("2026-06-01"), Balance: 1000},
+			alerts: []Alert{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Project(Input{
+				Start:    date("2026-06-01"),
+				Days:     7,
+				Accounts: []Account{tt.account},
+				Events:   tt.events,
+			})
+
+			if len(f.Accounts) != 1 {
+				t.Fatalf("Project() returned %d accounts, want 1", len(f.Accounts))
+			}
+			p := f.Accounts[0]
+			if len(p.Points) != 7 {
+				t.Fatalf("Project() returned %d points, want 7", len(p.Points))
+			}
+			if got := p.Points[len(p.Points)-1].Balance; got != tt.final {
+				t.Errorf("final balance = %d, want %d", got, tt.final)
+			}
+			if p.Lowest != tt.lowest {
+				t.Errorf("lowest = %+v, want %+v", p.Lowest, tt.lowest)
+			}
+			if len(p.Alerts) != len(tt.alerts) {
+				t.Fatalf("alerts = %+v, want %+v", p.Alerts, tt.alerts)
+			}
+			for i := range p.Alerts {
+				if p.Alerts[i] != tt.alerts[i] {
+					t.Errorf("alert %d = %+v, want %+v", i, p.Alerts[i], tt.alerts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProjectBudgets(t *testing.T) {
+	groceries := uint(7)
+	f := Project(Input{
+		Start:    date("2026-06-28"),
+		Days:     3,
+		Accounts: []Account{{ID: 1, Balance: 5000}},
+		Events: []Event{
+			{AccountID: 1, CategoryID: &groceries, Date: date("2026-06-29"), Amount: -400, Payee: "Market"},
+		},
+		Budgets: []Budget{{CategoryID: groceries, AccountID: 1, Monthly: 3000, Remaining: 1000}},
+	})
+
+	if len(f.Budgets) != 1 || f.Budgets[0].Amount != 600 {
+		t.Fatalf("Budgets = %+v, want 600 spent on category %d", f.Budgets, groceries)
+	}
+	if got := f.Accounts[0].Points[2].Balance; got != 4000 {
+		t.Errorf("final balance = %d, want 4000", got)
+	}
+}
+
+func TestBudgetEvents(t *testing.T) {
+	groceries := uint(7)
+	fuel := uint(8)
+
+	type spend struct {
+		date   string
+		amount int64
+	}
+
+	tests := []struct {
+		name       string
+		budgets    []Budget
+		known      []Event
+		start, end string
+		want       []spend
+	}{
+		{
+			name:    "remainder goes to the earliest days",
+			budgets: []Budget{{CategoryID: groceries, AccountID: 1, Remaining: 1000}},
+			start:   "2026-06-28",
+			end:     "2026-06-30",
+			want:    []spend{{"2026-06-28", -334}, {"2026-06-29", -333}, {"2026-06-30", -333}},
+		},
+		{
+			name:    "allowance smaller than the number of days",
+			budgets: []Budget{{CategoryID: groceries, AccountID: 1, Remaining: 2}},
+			start:   "2026-06-28",
+			end:     "2026-06-30",
+			want:    []spend{{"2026-06-28", -1}, {"2026-06-29", -1}},
+		},
+		{
+			name:    "known expenses are taken off the allowance",
+			budgets: []Budget{{CategoryID: groceries, AccountID: 1, Remaining: 1000}},
+			known: []Event{
+				{AccountID: 1, CategoryID: &groceries, Date: date("2026-06-29"), Amount: -400},
+				{AccountID: 1, CategoryID: &groceries, Date: date("2026-06-29"), Amount: 250},
+				{AccountID: 1, CategoryID: &fuel, Date: date("2026-06-29"), Amount: -900},
+			},
+			start: "2026-06-28",
+			end:   "2026-06-30",
+			want:  []spend{{"2026-06-28", -200}, {"2026-06-29", -200}, {"2026-06-30", -200}},
+		},
+		{
+			name:    "fully covered budget adds nothing",
+			budgets: []Budget{{CategoryID: groceries, AccountID: 1, Remaining: 1000}},
+			known: []Event{
+				{AccountID: 1, CategoryID: &groceries, Date: date("2026-06-29"), Amount: -1500},
+			},
+			start: "2026-06-28",
+			end:   "2026-06-30",
+			want:  nil,
+		},
+		{
+			name:    "later months use the monthly allowance",
+			budgets: []Budget{{CategoryID: groceries, AccountID: 1, Monthly: 3100, Remaining: 100}},
+			start:   "2026-06-29",
+			end:     "2026-07-02",
+			want:    []spend{{"2026-06-29", -50}, {"2026-06-30", -50}, {"2026-07-01", -100}, {"2026-07-02", -100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := budgetEvents(tt.budgets, tt.known, date(tt.start), date(tt.end))
+			if len(got) != len(tt.want) {
+				t.Fatalf("budgetEvents() = %+v, want %+v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				e := got[i]
+				if !e.Date.Equal(date(w.date)) || e.Amount != w.amount || e.Source != SourceBudget ||
+					e.CategoryID == nil || *e.CategoryID != groceries {
+					t.Errorf("event %d = %+v, want %s %d", i, e, w.date, w.amount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package goal works out how far along a savings goal is and whether it will
+// be reached in time, from its current amount and recent contributions. Like
+// package budget it has no database dependencies.
+package goal
+
+import (
+	"math"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusReached    Status = "reached"
+	StatusOnTrack    Status = "on_track"
+	StatusBehind     Status = "behind"
+	StatusInProgress Status = "in_progress"
+)
+
+// Input describes a goal at a point in time. Contributions holds the net
+// amount put towards the goal in each recent month, oldest first, including
+// the current one.
+type Input struct {
+	Target        int64
+	TargetDate    *time.Time
+	Current       int64
+	Contributions []int64
+}
+
+// Progress is the evaluated state of a goal.
+//
+// RequiredMonthly is what has to be put aside every month, this one included,
+// to hit the target date; it is nil when the goal has no date or is already
+// reached. ProjectedDate is when the goal will be reached at the average
+// monthly contribution, and nil when that average is not positive.
+type Progress struct {
+	Current         int64      `json:"current"`
+	Target          int64      `json:"target"`
+	Remaining       int64      `json:"remaining"`
+	PercentComplete float64    `json:"percent_complete"`
+	AverageMonthly  int64      `json:"average_monthly"`
+	RequiredMonthly *int64     `json:"required_monthly"`
+	ProjectedDate   *time.Time `json:"projected_date"`
+	Status          Status     `json:"status"`
+}
+
+// Evaluate computes the progress of in as of today. A goal with a target date
+// is on track when its projected date is not after the target date, and
+// behind otherwise; a goal without one is simply in progress.
+func Evaluate(in Input, today time.Time) Progress {
+	today = day(today)
+
+	p := Progress{
+		Current:   in.Current,
+		Target:    in.Target,
+		Remaining: max(in.Target-in.Current, 0),
+	}
+
+	if in.Target > 0 {
+		p.PercentComplete = math.Min(math.Round(float64(in.Current)*1000/float64(in.Target))/10, 100)
+	}
+
+	if len(in.Contributions) > 0 {
+		var total int64
+		for _, c := range in.Contributions {
+			total += c
+		}
+		p.AverageMonthly = total / int64(len(in.Contributions))
+	}
+
+	if p.Remaining == 0 {
+		p.Status = StatusReached
+		return p
+	}
+
+	if p.AverageMonthly > 0 {
+		projected := today.AddDate(0, int(ceilDiv(p.Remaining, p.AverageMonthly)), 0)
+		p.ProjectedDate = &projected
+	}
+
+	if in.TargetDate == nil {
+		p.Status = StatusInProgress
+		return p
+	}
+
+	required := ceilDiv(p.Remaining, int64(monthsUntil(today, day(*in.TargetDate))))
+	p.RequiredMonthly = &required
+
+	if p.ProjectedDate != nil && !p.ProjectedDate.After(day(*in.TargetDate)) {
+		p.Status = StatusOnTrack
+	} else {
+		p.Status = StatusBehind
+	}
+
+	return p
+}
+
+// monthsUntil counts the months left to contribute in, the current month
+// included. It is at least 1 so an overdue goal needs everything now.
+func monthsUntil(today, target time.Time) int {
+	months := (target.Year()-today.Year())*12 + int(target.Month()-today.Month()) + 1
+	return max(months, 1)
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
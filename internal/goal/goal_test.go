@@ -0,0 +1,155 @@
+package goal
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func datePtr(s string) *time.Time {
+	t := date(s)
+	return &t
+}
+
+func amount(n int64) *int64 { return &n }
+
+func TestEvaluate(t *testing.T) {
+	today := time.Date(2026, time.March, 15, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   Input
+		want Progress
+	}{
+		{
+			name: "reached",
+			in:   Input{Target: 1000, Current: 1200, TargetDate: datePtr("2026-12-31"), Contributions: []int64{400}},
+			want: Progress{Current: 1200, Target: 1000, PercentComplete: 100, AverageMonthly: 400, Status: StatusReached},
+		},
+		{
+			name: "zero target",
+			in:   Input{},
+			want: Progress{Status: StatusReached},
+		},
+		{
+			name: "on track",
+			in:   Input{Target: 12000, Current: 6000, TargetDate: datePtr("2026-09-30"), Contributions: []int64{800, 1000, 1200}},
+			want: Progress{
+				Current: 6000, Target: 12000, Remaining: 6000, PercentComplete: 50, AverageMonthly: 1000,
+				RequiredMonthly: amount(858), ProjectedDate: datePtr("2026-09-15"), Status: StatusOnTrack,
+			},
+		},
+		{
+			name: "behind",
+			in:   Input{Target: 12000, Current: 6000, TargetDate: datePtr("2026-06-30"), Contributions: []int64{1000}},
+			want: Progress{
+				Current: 6000, Target: 12000, Remaining: 6000, PercentComplete: 50, AverageMonthly: 1000,
+				RequiredMonthly: amount(1500), ProjectedDate: datePtr("2026-09-15"), Status: StatusBehind,
+			},
+		},
+		{
+			name: "overdue needs everything this month",
+			in:   Input{Target: 12000, Current: 6000, TargetDate: datePtr("2026-01-31"), Contributions: []int64{1000}},
+			want: Progress{
+				Current: 6000, Target: 12000, Remaining: 6000, PercentComplete: 50, AverageMonthly: 1000,
+				RequiredMonthly: amount(6000), ProjectedDate: datePtr("2026-09-15"), Status: StatusBehind,
+			},
+		},
+		{
+			name: "due this month",
+			in:   Input{Target: 3000, Current: 1000, TargetDate: datePtr("2026-03-31"), Contributions: []int64{2000}},
+			want: Progress{
+				Current: 1000, Target: 3000, Remaining: 2000, PercentComplete: 33.3, AverageMonthly: 2000,
+				RequiredMonthly: amount(2000), ProjectedDate: datePtr("2026-04-15"), Status: StatusBehind,
+			},
+		},
+		{
+			name: "zero average with a target date",
+			in:   Input{Target: 12000, Current: 6000, TargetDate: datePtr("2026-08-31"), Contributions: []int64{500, -500}},
+			want: Progress{
+				Current: 6000, Target: 12000, Remaining: 6000, PercentComplete: 50,
+				RequiredMonthly: amount(1000), Status: StatusBehind,
+			},
+		},
+		{
+			name: "negative average without a target date",
+			in:   Input{Target: 12000, Current: 6000, Contributions: []int64{-300}},
+			want: Progress{
+				Current: 6000, Target: 12000, Remaining: 6000, PercentComplete: 50, AverageMonthly: -300,
+				Status: StatusInProgress,
+			},
+		},
+		{
+			name: "no contributions without a target date",
+			in:   Input{Target: 500, Current: -100},
+			want: Progress{Current: -100, Target: 500, Remaining: 600, PercentComplete: -20, Status: StatusInProgress},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.in, today)
+
+			if got.Current != tt.want.Current || got.Target != tt.want.Target || got.Remaining != tt.want.Remaining ||
+				got.PercentComplete != tt.want.PercentComplete || got.AverageMonthly != tt.want.AverageMonthly ||
+				got.Status != tt.want.Status {
+				t.Errorf("Evaluate() = %+v, want %+v", got, tt.want)
+			}
+			if !sameAmount(got.RequiredMonthly, tt.want.RequiredMonthly) {
+				t.Errorf("RequiredMonthly = %v, want %v", deref(got.RequiredMonthly), deref(tt.want.RequiredMonthly))
+			}
+			if !sameDate(got.ProjectedDate, tt.want.ProjectedDate) {
+				t.Errorf("ProjectedDate = %v, want %v", got.ProjectedDate, tt.want.ProjectedDate)
+			}
+		})
+	}
+}
+
+func TestMonthsUntil(t *testing.T) {
+	today := date("2026-03-15")
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"2026-03-31", 1},
+		{"2026-04-01", 2},
+		{"2027-02-28", 12},
+		{"2026-02-28", 1},
+		{"2025-01-01", 1},
+	}
+
+	for _, tt := range tests {
+		if got := monthsUntil(today, date(tt.target)); got != tt.want {
+			t.Errorf("monthsUntil(%s) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+}
+
+func sameAmount(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func deref(n *int64) any {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
@@ -0,0 +1,217 @@
+// Package importer turns bank statement files into rows that can be previewed
+// and then posted as transactions.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nelsonfrank/finance-tracker/internal/money"
+)
+
+// Mapping tells ParseCSV which zero-based column holds each field and how
+// dates and numbers are written. Either Amount or at least one of Debit and
+// Credit must be set; debits are always treated as money leaving the account.
+type Mapping struct {
+	HasHeader        bool   `json:"has_header"`
+	Delimiter        string `json:"delimiter" validate:"omitempty,len=1"`
+	Date             int    `json:"date" validate:"gte=0"`
+	Amount           *int   `json:"amount" validate:"omitempty,gte=0"`
+	Debit            *int   `json:"debit" validate:"omitempty,gte=0"`
+	Credit           *int   `json:"credit" validate:"omitempty,gte=0"`
+	Payee            *int   `json:"payee" validate:"omitempty,gte=0"`
+	Memo             *int   `json:"memo" validate:"omitempty,gte=0"`
+	DateFormat       string `json:"date_format" validate:"required,max=32"`
+	DecimalSeparator string `json:"decimal_separator" validate:"omitempty,oneof=. ,"`
+	InvertAmount     bool   `json:"invert_amount"`
+}
+
+// Row is one parsed line of a statement. Line is the 1-based line number in
+// the source file, or the position of the transaction for formats without
+// lines, and Errors lists every problem found on it; a row with errors must
+// not be imported. Duplicate marks a row whose FITID is already on the
+// account; it is skipped on commit.
+type Row struct {
+	Line      int       `json:"line"`
+	Date      time.Time `json:"date"`
+	Amount    int64     `json:"amount"`
+	Payee     string    `json:"payee"`
+	Memo      string    `json:"memo"`
+	FITID     string    `json:"fitid,omitempty"`
+	Duplicate bool      `json:"duplicate,omitempty"`
+	Errors    []string  `json:"errors,omitempty"`
+}
+
+var ErrInvalidMapping = errors.New("invalid column mapping")
+
+// ParseCSV reads every record of r according to m. Amounts are converted to
+// minor units using exponent decimal places. Problems with individual rows are
+// reported on the row; only an unreadable file or unusable mapping returns an
+// error.
+func ParseCSV(r io.Reader, m Mapping, exponent int) ([]Row, error) {
+	if m.Amount == nil && m.Debit == nil && m.Credit == nil {
+		return nil, ErrInvalidMapping
+	}
+
+	layout, err := DateLayout(m.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	decimalSep := '.'
+	if m.DecimalSeparator != "" {
+		decimalSep, _ = utf8.DecodeRuneInString(m.DecimalSeparator)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(m.Delimiter)
+	}
+
+	rows := []Row{}
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if first {
+			first = false
+			if m.HasHeader {
+				continue
+			}
+		}
+		if isBlank(record) {
+			continue
+		}
+
+		rows = append(rows, parseRecord(record, line, m, layout, exponent, decimalSep))
+	}
+
+	return rows, nil
+}
+
+func parseRecord(record []string, line int, m Mapping, layout string, exponent int, decimalSep rune) Row {
+	row := Row{Line: line}
+
+	field := func(col *int) (string, bool) {
+		if col == nil {
+			return "", false
+		}
+		if *col >= len(record) {
+			row.Errors = append(row.Errors, fmt.Sprintf("column %d is missing", *col))
+			return "", false
+		}
+		return strings.TrimSpace(record[*col]), true
+	}
+
+	if v, ok := field(&m.Date); ok {
+		d, err := time.Parse(layout, v)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid date %q", v))
+		}
+		row.Date = d
+	}
+
+	if v, ok := field(m.Amount); ok {
+		amount, err := money.Parse(v, exponent, decimalSep)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid amount %q", v))
+		}
+		row.Amount = amount
+	} else if m.Amount == nil {
+		var found bool
+		if v, ok := field(m.Debit); ok && v != "" {
+			amount, err := money.Parse(v, exponent, decimalSep)
+			if err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("invalid debit %q", v))
+			}
+			row.Amount -= abs(amount)
+			found = true
+		}
+		if v, ok := field(m.Credit); ok && v != "" {
+			amount, err := money.Parse(v, exponent, decimalSep)
+			if err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("invalid credit %q", v))
+			}
+			row.Amount += abs(amount)
+			found = true
+		}
+		if !found {
+			row.Errors = append(row.Errors, "debit and credit are both empty")
+		}
+	}
+
+	if m.InvertAmount {
+		row.Amount = -row.Amount
+	}
+
+	if v, ok := field(m.Payee); ok {
+		row.Payee = v
+	}
+	if v, ok := field(m.Memo); ok {
+		row.Memo = v
+	}
+
+	if len(row.Errors) == 0 && row.Amount == 0 {
+		row.Errors = append(row.Errors, "amount is zero")
+	}
+
+	return row
+}
+
+var dateTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MMM", "Jan",
+	"MM", "01",
+	"M", "1",
+	"DD", "02",
+	"D", "2",
+)
+
+// DateLayout converts a human date format such as "DD/MM/YYYY" into a Go time
+// layout. Formats that are already Go layouts are returned unchanged.
+func DateLayout(format string) (string, error) {
+	if format == "" {
+		return "", ErrInvalidMapping
+	}
+	if strings.Contains(format, "2006") || strings.Contains(format, "06") {
+		return format, nil
+	}
+
+	layout := dateTokens.Replace(format)
+	if layout == format {
+		return "", fmt.Errorf("%w: unknown date format %q", ErrInvalidMapping, format)
+	}
+
+	return layout, nil
+}
+
+func isBlank(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func col(n int) *int { return &n }
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		mapping  Mapping
+		exponent int
+		want     []Row
+	}{
+		{
+			name:  "amount column with header",
+			input: "Date,Payee,Amount\n2026-01-05,Grocer,-12.34\n2026-01-06,Employer,\"1,500.00\"\n",
+			mapping: Mapping{
+				HasHeader: true, Date: 0, Payee: col(1), Amount: col(2),
+				DateFormat: "YYYY-MM-DD",
+			},
+			exponent: 2,
+			want: []Row{
+				{Line: 2, Date: date(2026, 1, 5), Amount: -1234, Payee: "Grocer"},
+				{Line: 3, Date: date(2026, 1, 6), Amount: 150000, Payee: "Employer"},
+			},
+		},
+		{
+			name:  "debit and credit columns",
+			input: "05/01/2026,Rent,800.00,,January\n06/01/2026,Refund,,-20.00,\n",
+			mapping: Mapping{
+				Date: 0, Payee: col(1), Debit: col(2), Credit: col(3), Memo: col(4),
+				DateFormat: "DD/MM/YYYY",
+			},
+			exponent: 2,
+			want: []Row{
+				{Line: 1, Date: date(2026, 1, 5), Amount: -80000, Payee: "Rent", Memo: "January"},
+				{Line: 2, Date: date(2026, 1, 6), Amount: 2000, Payee: "Refund"},
+			},
+		},
+		{
+			name:  "semicolons, decimal comma and inverted amounts",
+			input: "2026-01-05;Card payment;1.234,50\n\n2026-01-06;Cashback;-2,00\n",
+			mapping: Mapping{
+				Delimiter: ";", Date: 0, Payee: col(1), Amount: col(2),
+				DateFormat: "2006-01-02", DecimalSeparator: ",", InvertAmount: true,
+			},
+			exponent: 2,
+			want: []Row{
+				{Line: 1, Date: date(2026, 1, 5), Amount: -123450, Payee: "Card payment"},
+				{Line: 3, Date: date(2026, 1, 6), Amount: 200, Payee: "Cashback"},
+			},
+		},
+		{
+			name:  "zero exponent currency",
+			input: "2026-01-05,1500\n",
+			mapping: Mapping{
+				Date: 0, Amount: col(1), DateFormat: "YYYY-MM-DD",
+			},
+			exponent: 0,
+			want: []Row{
+				{Line: 1, Date: date(2026, 1, 5), Amount: 1500},
+			},
+		},
+		{
+			name:  "row problems are reported on the row",
+			input: "2026-13-01,Shop,1E5\n2026-01-02,Shop\n2026-01-03,Shop,\"0,00\"\n2026-01-04,Shop,12.34\n",
+			mapping: Mapping{
+				Date: 0, Payee: col(1), Amount: col(2), DateFormat: "YYYY-MM-DD",
+				DecimalSeparator: ",",
+			},
+			exponent: 2,
+			want: []Row{
+				{Line: 1, Payee: "Shop", Errors: []string{`invalid date "2026-13-01"`, `invalid amount "1E5"`}},
+				{Line: 2, Date: date(2026, 1, 2), Payee: "Shop", Errors: []string{"column 2 is missing"}},
+				{Line: 3, Date: date(2026, 1, 3), Payee: "Shop", Errors: []string{"amount is zero"}},
+				{Line: 4, Date: date(2026, 1, 4), Payee: "Shop", Errors: []string{`invalid amount "12.34"`}},
+			},
+		},
+		{
+			name:  "empty debit and credit",
+			input: "2026-01-05,Shop,,\n",
+			mapping: Mapping{
+				Date: 0, Payee: col(1), Debit: col(2), Credit: col(3), DateFormat: "YYYY-MM-DD",
+			},
+			exponent: 2,
+			want: []Row{
+				{Line: 1, Date: date(2026, 1, 5), Payee: "Shop", Errors: []string{"debit and credit are both empty"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.input), tt.mapping, tt.exponent)
+			if err != nil {
+				t.Fatalf("ParseCSV() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseCSV() returned %d rows, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !sameRow(got[i], tt.want[i]) {
+					t.Errorf("row %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCSVInvalidMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+	}{
+		{"no amount columns", Mapping{Date: 0, DateFormat: "YYYY-MM-DD"}},
+		{"no date format", Mapping{Date: 0, Amount: col(1)}},
+		{"unknown date format", Mapping{Date: 0, Amount: col(1), DateFormat: "Q/W/E"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader("2026-01-05,1\n"), tt.mapping, 2)
+			if !errors.Is(err, ErrInvalidMapping) {
+				t.Errorf("ParseCSV() error = %v, want %v", err, ErrInvalidMapping)
+			}
+		})
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"YYYY-MM-DD", "2006-01-02"},
+		{"DD/MM/YYYY", "02/01/2006"},
+		{"M/D/YY", "1/2/06"},
+		{"DD MMM YYYY", "02 Jan 2006"},
+		{"02.01.2006", "02.01.2006"},
+	}
+
+	for _, tt := range tests {
+		got, err := DateLayout(tt.format)
+		if err != nil || got != tt.want {
+			t.Errorf("DateLayout(%q) = %q, %v, want %q", tt.format, got, err, tt.want)
+		}
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func sameRow(a, b Row) bool {
+	if a.Line != b.Line || !a.Date.Equal(b.Date) || a.Amount != b.Amount ||
+		a.Payee != b.Payee || a.Memo != b.Memo || len(a.Errors) != len(b.Errors) {
+		return false
+	}
+	for i := range a.Errors {
+		if a.Errors[i] != b.Errors[i] {
+			return false
+		}
+	}
+	return true
+}
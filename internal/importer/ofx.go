@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nelsonfrank/finance-tracker/internal/money"
+	"github.com/nelsonfrank/finance-tracker/internal/ofx"
+)
+
+// FromOFX converts the transactions of an OFX statement into rows. Amounts
+// are converted to minor units using exponent decimal places.
+func FromOFX(st ofx.Statement, exponent int) []Row {
+	rows := make([]Row, 0, len(st.Transactions))
+	seen := map[string]bool{}
+
+	for i, t := range st.Transactions {
+		row := Row{
+			Line:  i + 1,
+			Date:  t.Posted,
+			Payee: t.Name,
+			Memo:  t.Memo,
+			FITID: t.FITID,
+		}
+
+		// OFX mandates '.', but some banks write amounts with a decimal comma.
+		decimalSep := '.'
+		if strings.Contains(t.Amount, ",") && !strings.Contains(t.Amount, ".") {
+			decimalSep = ','
+		}
+
+		amount, err := money.Parse(t.Amount, exponent, decimalSep)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid amount %q", t.Amount))
+		}
+		row.Amount = amount
+
+		switch {
+		case t.FITID == "":
+			row.Errors = append(row.Errors, "missing FITID")
+		case seen[t.FITID]:
+			row.Duplicate = true
+		}
+		seen[t.FITID] = true
+
+		if len(row.Errors) == 0 && row.Amount == 0 {
+			row.Errors = append(row.Errors, "amount is zero")
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -3,12 +3,13 @@ package mailer
 import "embed"
 
 const (
-	FromName            = "Financial Tracker"
-	maxRetires          = 3
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName              = "Financial Tracker"
+	maxRetires            = 3
+	UserWelcomeTemplate   = "user_invitation.tmpl"
+	ResetPasswordTemplate = "reset_password.tmpl"
 )
 
-// go:embed "templates"
+//go:embed templates
 var FS embed.FS
 
 type Client interface {
@@ -0,0 +1,176 @@
+// Package money handles amounts expressed in a currency's minor units, e.g.
+// cents for USD. Amounts are always int64 and never pass through a float.
+package money
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// exponents lists currencies whose minor unit is not 1/100 of the major unit.
+var exponents = map[string]int{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KMF": 0, "KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3,
+	"PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// Exponent returns the number of decimal places used by the minor unit of the
+// ISO 4217 currency code.
+func Exponent(currency string) int {
+	if e, ok := exponents[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// Parse converts a decimal string such as "-1,234.56" into minor units with
+// the given exponent. decimalSep is the character separating the fraction;
+// spaces, apostrophes and whichever of '.' or ',' is not decimalSep separate
+// thousands, and are only accepted between groups of three digits so that
+// "12.34" read with a ',' decimal separator is an error rather than 1234. A
+// leading or trailing minus sign and accounting-style parentheses both make
+// the amount negative. A currency symbol or ISO 4217 code before or after
+// the number is ignored; any other letter is an error. More fraction digits
+// than the exponent allows is an error rather than a silent rounding.
+func Parse(s string, exponent int, decimalSep rune) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	s, minus, err := trimAffixes(s)
+	if err != nil || (minus && negative) {
+		return 0, ErrInvalidAmount
+	}
+	negative = negative || minus
+
+	whole, frac := s, ""
+	if i := strings.IndexRune(s, decimalSep); i >= 0 {
+		whole, frac = s[:i], s[i+utf8.RuneLen(decimalSep):]
+	}
+
+	whole, ok := ungroup(whole, decimalSep)
+	if !ok || !isDigits(frac) || whole+frac == "" || len(frac) > exponent {
+		return 0, ErrInvalidAmount
+	}
+
+	digits := whole + frac + strings.Repeat("0", exponent-len(frac))
+	if len(digits) > 18 {
+		return 0, ErrInvalidAmount
+	}
+
+	var n int64
+	for _, d := range digits {
+		n = n*10 + int64(d-'0')
+	}
+	if negative {
+		n = -n
+	}
+
+	return n, nil
+}
+
+// trimAffixes strips spaces, a sign and a currency symbol or code from both
+// ends of s and reports whether a minus sign was among them.
+func trimAffixes(s string) (string, bool, error) {
+	minus, signed, currency := false, false, false
+
+	for {
+		s = strings.TrimSpace(s)
+
+		switch {
+		case strings.HasPrefix(s, "-"), strings.HasPrefix(s, "+"):
+			if signed {
+				return "", false, ErrInvalidAmount
+			}
+			signed, minus = true, s[0] == '-'
+			s = s[1:]
+			continue
+		case strings.HasSuffix(s, "-"):
+			if signed {
+				return "", false, ErrInvalidAmount
+			}
+			signed, minus = true, true
+			s = s[:len(s)-1]
+			continue
+		}
+
+		if currency {
+			return s, minus, nil
+		}
+
+		if r, size := utf8.DecodeRuneInString(s); isSymbol(r) {
+			s, currency = s[size:], true
+		} else if r, size := utf8.DecodeLastRuneInString(s); isSymbol(r) {
+			s, currency = s[:len(s)-size], true
+		} else if len(s) > 3 && isCode(s[:3]) {
+			s, currency = s[3:], true
+		} else if len(s) > 3 && isCode(s[len(s)-3:]) {
+			s, currency = s[:len(s)-3], true
+		} else {
+			return s, minus, nil
+		}
+	}
+}
+
+// ungroup removes thousands separators from the whole part of a number,
+// making sure they sit between groups of three digits.
+func ungroup(s string, decimalSep rune) (string, bool) {
+	groups := strings.FieldsFunc(s, func(r rune) bool {
+		return r != decimalSep && strings.ContainsRune(" '\u00a0.,", r)
+	})
+	if len(groups) == 0 {
+		return "", s == ""
+	}
+	if len(groups) == 1 {
+		return groups[0], groups[0] == s && isDigits(s)
+	}
+
+	// FieldsFunc drops empty groups, so their lengths adding up to less
+	// than s minus one separator each means two separators were adjacent
+	// or one was at an end.
+	total := 0
+	for i, g := range groups {
+		if !isDigits(g) || len(g) > 3 || (i > 0 && len(g) != 3) {
+			return "", false
+		}
+		total += len(g)
+	}
+	if utf8.RuneCountInString(s) != total+len(groups)-1 {
+		return "", false
+	}
+
+	return strings.Join(groups, ""), true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isSymbol(r rune) bool {
+	return strings.ContainsRune("$€£¥₹₩₦₱", r)
+}
+
+func isCode(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
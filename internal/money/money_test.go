@@ -0,0 +1,74 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in         string
+		exponent   int
+		decimalSep rune
+		want       int64
+		wantErr    bool
+	}{
+		{in: "1234.56", exponent: 2, decimalSep: '.', want: 123456},
+		{in: "-1,234.56", exponent: 2, decimalSep: '.', want: -123456},
+		{in: "1,000,000.00", exponent: 2, decimalSep: '.', want: 100000000},
+		{in: "1 234,56", exponent: 2, decimalSep: ',', want: 123456},
+		{in: "1.234,56", exponent: 2, decimalSep: ',', want: 123456},
+		{in: "1'234.5", exponent: 2, decimalSep: '.', want: 123450},
+		{in: "1 234,5", exponent: 2, decimalSep: ',', want: 123450},
+		{in: "(12.00)", exponent: 2, decimalSep: '.', want: -1200},
+		{in: "12.00-", exponent: 2, decimalSep: '.', want: -1200},
+		{in: "+5", exponent: 2, decimalSep: '.', want: 500},
+		{in: ".5", exponent: 2, decimalSep: '.', want: 50},
+		{in: "12.", exponent: 2, decimalSep: '.', want: 1200},
+		{in: "$1,000", exponent: 2, decimalSep: '.', want: 100000},
+		{in: "-$12.50", exponent: 2, decimalSep: '.', want: -1250},
+		{in: "$-12.50", exponent: 2, decimalSep: '.', want: -1250},
+		{in: "12,50 €", exponent: 2, decimalSep: ',', want: 1250},
+		{in: "12.50 EUR", exponent: 2, decimalSep: '.', want: 1250},
+		{in: "USD 12.50", exponent: 2, decimalSep: '.', want: 1250},
+		{in: "1234", exponent: 0, decimalSep: '.', want: 1234},
+		{in: "1.234", exponent: 3, decimalSep: '.', want: 1234},
+		{in: "  42  ", exponent: 2, decimalSep: '.', want: 4200},
+
+		{in: "", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "-", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "$", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "USD", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1E5", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "12abc", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "usd 12", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "12.34", exponent: 2, decimalSep: ',', wantErr: true},
+		{in: "1,23.45", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1,2345.00", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "12,345,67", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1,000,", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: ",500", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1,,000", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1.2.3", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1.5,00", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "12.345", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1.5", exponent: 0, decimalSep: '.', wantErr: true},
+		{in: "--5", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "-5-", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "(-5)", exponent: 2, decimalSep: '.', wantErr: true},
+		{in: "1234567890123456789", exponent: 0, decimalSep: '.', wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.exponent, tt.decimalSep)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Parse(%q, %d, %q) = %d, %v, want ErrInvalidAmount", tt.in, tt.exponent, tt.decimalSep, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q, %d, %q) = %d, %v, want %d", tt.in, tt.exponent, tt.decimalSep, got, err, tt.want)
+		}
+	}
+}
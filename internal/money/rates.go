@@ -0,0 +1,165 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRate = errors.New("invalid exchange rate")
+	ErrNoRate      = errors.New("no exchange rate available")
+)
+
+// Rate says that on Date one unit of Base was worth Value units of Quote.
+type Rate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Value *big.Rat
+}
+
+// ParseRate parses a positive decimal exchange rate such as "1.0845".
+// Fractions and exponents are rejected so that the stored value is exactly
+// what was uploaded.
+func ParseRate(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "/eE") {
+		return nil, ErrInvalidRate
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+
+	return r, nil
+}
+
+// Convert turns amount, in minor units of from, into minor units of to using
+// rate, the value of one major unit of from in major units of to. The result
+// is rounded half away from zero.
+func Convert(amount int64, from, to string, rate *big.Rat) int64 {
+	if strings.EqualFold(from, to) {
+		return amount
+	}
+
+	v := new(big.Rat).SetInt64(amount)
+	v.Mul(v, rate)
+
+	shift := Exponent(to) - Exponent(from)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+	if shift >= 0 {
+		v.Mul(v, scale)
+	} else {
+		v.Quo(v, scale)
+	}
+
+	return roundHalfAway(v)
+}
+
+func roundHalfAway(v *big.Rat) int64 {
+	num := new(big.Int).Set(v.Num())
+	den := v.Denom()
+
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if m.Mul(m, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if negative {
+		q.Neg(q)
+	}
+
+	return q.Int64()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// RateTable answers "what was the rate between two currencies around this
+// date" from an in-memory set of rates.
+type RateTable struct {
+	pairs map[string][]Rate
+}
+
+func pairKey(base, quote string) string {
+	return strings.ToUpper(base) + "/" + strings.ToUpper(quote)
+}
+
+// NewRateTable indexes rates by currency pair.
+func NewRateTable(rates []Rate) *RateTable {
+	t := &RateTable{pairs: map[string][]Rate{}}
+	for _, r := range rates {
+		k := pairKey(r.Base, r.Quote)
+		t.pairs[k] = append(t.pairs[k], r)
+	}
+	for _, rs := range t.pairs {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].Date.Before(rs[j].Date) })
+	}
+	return t
+}
+
+// Nearest returns the rate from one currency to another closest to date,
+// preferring the earlier one on a tie. A rate stored for the opposite
+// direction is inverted when that is closer or the only one available.
+func (t *RateTable) Nearest(from, to string, date time.Time) (*big.Rat, bool) {
+	if strings.EqualFold(from, to) {
+		return big.NewRat(1, 1), true
+	}
+
+	direct, dOK := nearest(t.pairs[pairKey(from, to)], date)
+	inverse, iOK := nearest(t.pairs[pairKey(to, from)], date)
+
+	switch {
+	case dOK && (!iOK || distance(direct.Date, date) <= distance(inverse.Date, date)):
+		return direct.Value, true
+	case iOK:
+		return new(big.Rat).Inv(inverse.Value), true
+	default:
+		return nil, false
+	}
+}
+
+// Convert converts amount from one currency to another with the rate nearest
+// to date.
+func (t *RateTable) Convert(amount int64, from, to string, date time.Time) (int64, error) {
+	rate, ok := t.Nearest(from, to, date)
+	if !ok {
+		return 0, ErrNoRate
+	}
+	return Convert(amount, from, to, rate), nil
+}
+
+func nearest(rates []Rate, date time.Time) (Rate, bool) {
+	if len(rates) == 0 {
+		return Rate{}, false
+	}
+
+	i := sort.Search(len(rates), func(i int) bool { return !rates[i].Date.Before(date) })
+	switch {
+	case i == 0:
+		return rates[0], true
+	case i == len(rates):
+		return rates[i-1], true
+	case distance(rates[i-1].Date, date) <= distance(rates[i].Date, date):
+		return rates[i-1], true
+	default:
+		return rates[i], true
+	}
+}
+
+func distance(a, b time.Time) time.Duration {
+	if d := a.Sub(b); d >= 0 {
+		return d
+	}
+	return b.Sub(a)
+}
@@ -0,0 +1,149 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func rat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic(s)
+	}
+	return r
+}
+
+func day(n int) time.Time {
+	return time.Date(2026, time.January, n, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "1.0845", want: "2169/2000"},
+		{in: " 0.5 ", want: "1/2"},
+		{in: "150", want: "150"},
+		{in: "", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "-1.2", wantErr: true},
+		{in: "1/3", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRate) {
+				t.Errorf("ParseRate(%q) error = %v, want ErrInvalidRate", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRate(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got.Cmp(rat(tt.want)) != 0 {
+			t.Errorf("ParseRate(%q) = %s, want %s", tt.in, got.RatString(), tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		from, to string
+		rate     string
+		want     int64
+	}{
+		{"same currency", 12345, "USD", "usd", "2", 12345},
+		{"exact", 10000, "EUR", "USD", "1.0845", 10845},
+		{"half rounds up", 1, "EUR", "USD", "1.5", 2},
+		{"negative half rounds away from zero", -1, "EUR", "USD", "1.5", -2},
+		{"below half rounds down", 1, "EUR", "USD", "1.49", 1},
+		{"negative below half rounds towards zero", -1, "EUR", "USD", "1.49", -1},
+		{"to fewer decimals", 1050, "USD", "JPY", "150.25", 1578},
+		{"to no decimals exactly", 100, "USD", "JPY", "150", 150},
+		{"from no decimals", 1000, "JPY", "USD", "0.0067", 670},
+		{"to more decimals", 100, "USD", "KWD", "0.307", 307},
+		{"large amounts stay exact", 123456789012, "EUR", "USD", "1.1", 135802467913},
+		{"repeating fraction", 100, "USD", "EUR", "1/3", 33},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.amount, tt.from, tt.to, rat(tt.rate)); got != tt.want {
+				t.Errorf("Convert(%d, %s, %s, %s) = %d, want %d", tt.amount, tt.from, tt.to, tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateTableNearest(t *testing.T) {
+	table := NewRateTable([]Rate{
+		{Date: day(5), Base: "EUR", Quote: "USD", Value: rat("1.20")},
+		{Date: day(1), Base: "EUR", Quote: "USD", Value: rat("1.10")},
+		{Date: day(7), Base: "usd", Quote: "eur", Value: rat("0.8")},
+		{Date: day(3), Base: "GBP", Quote: "USD", Value: rat("1.25")},
+	})
+
+	tests := []struct {
+		name     string
+		from, to string
+		date     time.Time
+		want     string
+		wantOK   bool
+	}{
+		{"same currency", "CHF", "chf", day(1), "1", true},
+		{"exact date", "EUR", "USD", day(1), "1.10", true},
+		{"closer earlier rate", "EUR", "USD", day(2), "1.10", true},
+		{"closer later rate", "EUR", "USD", day(4), "1.20", true},
+		{"equal distance prefers the earlier rate", "EUR", "USD", day(3), "1.10", true},
+		{"before every rate", "EUR", "USD", day(1).AddDate(0, -1, 0), "1.10", true},
+		{"ignores case", "eur", "usd", day(5), "1.20", true},
+		{"inverse only", "USD", "GBP", day(3), "0.8", true},
+		{"inverse when closer", "EUR", "USD", day(7), "1.25", true},
+		{"direct on equal distance to the inverse", "EUR", "USD", day(6), "1.20", true},
+		{"inverse when the direct rate is further", "USD", "EUR", day(2), "10/11", true},
+		{"direct when closer", "USD", "EUR", day(7), "0.8", true},
+		{"unknown pair", "EUR", "JPY", day(1), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := table.Nearest(tt.from, tt.to, tt.date)
+			if ok != tt.wantOK {
+				t.Fatalf("Nearest() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			want := rat(tt.want)
+			if got.Cmp(want) != 0 {
+				t.Errorf("Nearest() = %s, want %s", got.RatString(), want.RatString())
+			}
+		})
+	}
+}
+
+func TestRateTableConvert(t *testing.T) {
+	table := NewRateTable([]Rate{{Date: day(3), Base: "GBP", Quote: "USD", Value: rat("1.25")}})
+
+	got, err := table.Convert(10000, "USD", "GBP", day(10))
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if got != 8000 {
+		t.Errorf("Convert() = %d, want 8000", got)
+	}
+
+	if _, err := table.Convert(10000, "USD", "JPY", day(10)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("Convert() error = %v, want ErrNoRate", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Package notify delivers events that the user should hear about, such as a
+// savings goal being reached. Emitters decide how; the only one today writes
+// the event to the application log.
+package notify
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Kind string
+
+const (
+	GoalReached Kind = "goal.reached"
+	GoalBehind  Kind = "goal.behind"
+)
+
+type Event struct {
+	Kind    Kind           `json:"kind"`
+	UserID  uint           `json:"user_id"`
+	Subject string         `json:"subject"`
+	Data    map[string]any `json:"data,omitempty"`
+	At      time.Time      `json:"at"`
+}
+
+type Emitter interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+type logEmitter struct {
+	logger *zap.SugaredLogger
+}
+
+// NewLogEmitter returns an Emitter that logs every event at info level.
+func NewLogEmitter(logger *zap.SugaredLogger) Emitter {
+	return &logEmitter{logger}
+}
+
+func (e *logEmitter) Emit(_ context.Context, event Event) error {
+	e.logger.Infow("notification",
+		"kind", event.Kind,
+		"user_id", event.UserID,
+		"subject", event.Subject,
+		"data", event.Data,
+		"at", event.At,
+	)
+	return nil
+}
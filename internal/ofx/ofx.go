@@ -0,0 +1,144 @@
+// Package ofx reads bank and credit card statements from OFX and QFX files.
+// Both the SGML flavour of OFX 1.x, where leaf elements are not closed, and
+// the XML flavour of OFX 2.x are supported. QFX is OFX with extra Intuit
+// elements, which are ignored.
+package ofx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoStatement = errors.New("ofx: no statement found")
+	ErrMalformed   = errors.New("ofx: malformed document")
+)
+
+// Statement is one bank or credit card statement response.
+type Statement struct {
+	Currency     string        `json:"currency"`
+	BankID       string        `json:"bank_id,omitempty"`
+	AccountID    string        `json:"account_id"`
+	AccountType  string        `json:"account_type,omitempty"`
+	Start        time.Time     `json:"start"`
+	End          time.Time     `json:"end"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Transaction is a single STMTTRN element. Amount is kept as the decimal
+// string found in the file so callers can convert it to minor units exactly.
+type Transaction struct {
+	FITID    string    `json:"fitid"`
+	Type     string    `json:"type"`
+	Posted   time.Time `json:"posted"`
+	Amount   string    `json:"amount"`
+	Name     string    `json:"name"`
+	Memo     string    `json:"memo"`
+	CheckNum string    `json:"check_num,omitempty"`
+}
+
+// Parse reads every statement in an OFX document.
+func Parse(r io.Reader) ([]Statement, error) {
+	root, err := parseTree(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	for _, rs := range root.findAll("STMTRS", "CCSTMTRS") {
+		st, err := readStatement(rs)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, st)
+	}
+
+	if len(statements) == 0 {
+		return nil, ErrNoStatement
+	}
+
+	return statements, nil
+}
+
+func readStatement(rs *node) (Statement, error) {
+	st := Statement{
+		Currency:     strings.ToUpper(rs.value("CURDEF")),
+		Transactions: []Transaction{},
+	}
+
+	if acct := rs.first("BANKACCTFROM", "CCACCTFROM"); acct != nil {
+		st.BankID = acct.value("BANKID")
+		st.AccountID = acct.value("ACCTID")
+		st.AccountType = acct.value("ACCTTYPE")
+	}
+
+	list := rs.first("BANKTRANLIST")
+	if list == nil {
+		return st, nil
+	}
+
+	var err error
+	if v := list.value("DTSTART"); v != "" {
+		if st.Start, err = parseDate(v); err != nil {
+			return st, err
+		}
+	}
+	if v := list.value("DTEND"); v != "" {
+		if st.End, err = parseDate(v); err != nil {
+			return st, err
+		}
+	}
+
+	for _, n := range list.children {
+		if n.name != "STMTTRN" {
+			continue
+		}
+
+		posted, err := parseDate(n.value("DTPOSTED"))
+		if err != nil {
+			return st, err
+		}
+
+		st.Transactions = append(st.Transactions, Transaction{
+			FITID:    n.value("FITID"),
+			Type:     n.value("TRNTYPE"),
+			Posted:   posted,
+			Amount:   n.value("TRNAMT"),
+			Name:     firstNonEmpty(n.value("NAME"), n.value("PAYEE")),
+			Memo:     n.value("MEMO"),
+			CheckNum: n.value("CHECKNUM"),
+		})
+	}
+
+	return st, nil
+}
+
+// parseDate reads the date part of an OFX datetime such as
+// "20240131120000.000[-5:EST]". Statement dates are calendar days, so the time
+// and zone are dropped.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrMalformed, s)
+	}
+
+	d, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrMalformed, s)
+	}
+
+	return d, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
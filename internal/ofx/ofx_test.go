@@ -0,0 +1,152 @@
+package ofx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file string
+		want []Statement
+	}{
+		{
+			file: "sgml.ofx",
+			want: []Statement{{
+				Currency:    "USD",
+				BankID:      "121000248",
+				AccountID:   "0001234567",
+				AccountType: "CHECKING",
+				Start:       date("2026-01-01"),
+				End:         date("2026-01-31"),
+				Transactions: []Transaction{
+					{FITID: "2026010501", Type: "DEBIT", Posted: date("2026-01-05"), Amount: "-42.50", Name: "Ben & Jerry's", Memo: "POS PURCHASE"},
+					{FITID: "2026011201", Type: "CHECK", Posted: date("2026-01-12"), Amount: "-100.00", Name: "Check 1042", CheckNum: "1042"},
+					{FITID: "2026013001", Type: "CREDIT", Posted: date("2026-01-30"), Amount: "2500.00", Name: "Payroll"},
+				},
+			}},
+		},
+		{
+			file: "xml.qfx",
+			want: []Statement{{
+				Currency:  "EUR",
+				AccountID: "4111111111111111",
+				Start:     date("2026-02-01"),
+				End:       date("2026-02-28"),
+				Transactions: []Transaction{
+					{FITID: "A1", Type: "DEBIT", Posted: date("2026-02-14"), Amount: "-65.20", Name: "Café Central", Memo: "Dinner"},
+					{FITID: "A2", Type: "PAYMENT", Posted: date("2026-02-20"), Amount: "300.00", Name: "Payment - thank you"},
+				},
+			}},
+		},
+		{
+			file: "empty.ofx",
+			want: []Statement{{
+				Currency: "GBP",
+				Transactions: []Transaction{
+					{FITID: "E1", Type: "DEBIT", Posted: date("2026-03-03"), Amount: "-5.00"},
+					{FITID: "E2", Type: "DEBIT", Posted: date("2026-03-04"), Amount: "-7.50", Name: "Bakery"},
+				},
+			}},
+		},
+		{
+			file: "multi.ofx",
+			want: []Statement{
+				{
+					Currency:    "USD",
+					BankID:      "121000248",
+					AccountID:   "111",
+					AccountType: "CHECKING",
+					Start:       date("2026-04-01"),
+					End:         date("2026-04-30"),
+					Transactions: []Transaction{
+						{FITID: "C1", Type: "DEBIT", Posted: date("2026-04-10"), Amount: "-12.00", Name: "Parking"},
+					},
+				},
+				{
+					Currency:     "USD",
+					BankID:       "121000248",
+					AccountID:    "222",
+					AccountType:  "SAVINGS",
+					Start:        date("2026-04-01"),
+					End:          date("2026-04-30"),
+					Transactions: []Transaction{},
+				},
+				{
+					Currency:     "USD",
+					AccountID:    "333",
+					Transactions: []Transaction{},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := Parse(f)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() returned %d statements, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("statement %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"empty", "", ErrNoStatement},
+		{"not ofx", "Date,Amount,Payee\n2026-01-01,-5.00,Cafe\n", ErrNoStatement},
+		{"no statement", "<OFX><SIGNONMSGSRSV1><SONRS><CODE>0</SONRS></SIGNONMSGSRSV1></OFX>", ErrNoStatement},
+		{"unterminated tag", "<OFX><STMTRS><CURDEF", ErrMalformed},
+		{"unexpected end tag", "<OFX><STMTRS><CURDEF>USD</STMTRS></BANKMSGSRSV1></OFX>", ErrMalformed},
+		{
+			"invalid posted date",
+			"<OFX><STMTRS><BANKTRANLIST><STMTTRN><DTPOSTED>2026-01-05<TRNAMT>-1.00</STMTTRN></BANKTRANLIST></STMTRS></OFX>",
+			ErrMalformed,
+		},
+		{
+			"short statement date",
+			"<OFX><STMTRS><BANKTRANLIST><DTSTART>2026</BANKTRANLIST></STMTRS></OFX>",
+			ErrMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
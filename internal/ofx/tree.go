@@ -0,0 +1,138 @@
+package ofx
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// node is an element of the document. Leaf elements carry text, aggregates
+// carry children.
+type node struct {
+	name     string
+	text     string
+	children []*node
+}
+
+func (n *node) first(names ...string) *node {
+	for _, c := range n.children {
+		for _, name := range names {
+			if c.name == name {
+				return c
+			}
+		}
+	}
+	for _, c := range n.children {
+		if found := c.first(names...); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (n *node) findAll(names ...string) []*node {
+	var found []*node
+	for _, c := range n.children {
+		matched := false
+		for _, name := range names {
+			if c.name == name {
+				found = append(found, c)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			found = append(found, c.findAll(names...)...)
+		}
+	}
+	return found
+}
+
+// value returns the text of the direct child leaf called name.
+func (n *node) value(name string) string {
+	for _, c := range n.children {
+		if c.name == name {
+			return c.text
+		}
+	}
+	return ""
+}
+
+// parseTree builds the element tree of an OFX document. The OFX 1.x header
+// block, XML declarations and processing instructions before <OFX> are
+// skipped. Because SGML leaf elements have no end tag, an element is treated
+// as a leaf when text follows its start tag, and a later end tag for it is
+// optional. End tags close any still-open aggregates nested inside them.
+func parseTree(r *bufio.Reader) (*node, error) {
+	root := &node{}
+	stack := []*node{root}
+
+	var lastLeaf *node
+	for {
+		text, err := r.ReadString('<')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		text = strings.TrimSpace(strings.TrimSuffix(text, "<"))
+		if text != "" && len(stack) > 1 {
+			top := stack[len(stack)-1]
+			if len(top.children) == 0 {
+				// <TAG>value: the element just opened is a leaf.
+				stack = stack[:len(stack)-1]
+				top.text = html.UnescapeString(text)
+				lastLeaf = top
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		tag, err := r.ReadString('>')
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("%w: unterminated tag", ErrMalformed)
+			}
+			return nil, err
+		}
+		tag = strings.TrimSpace(strings.TrimSuffix(tag, ">"))
+
+		switch {
+		case tag == "" || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!"):
+			continue
+		case strings.HasPrefix(tag, "/"):
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			if lastLeaf != nil && lastLeaf.name == name {
+				lastLeaf = nil
+				continue
+			}
+			lastLeaf = nil
+
+			i := len(stack) - 1
+			for i > 0 && stack[i].name != name {
+				i--
+			}
+			if i == 0 {
+				return nil, fmt.Errorf("%w: unexpected </%s>", ErrMalformed, name)
+			}
+			// Anything still open above the matching element was an empty leaf.
+			stack = stack[:i]
+		default:
+			lastLeaf = nil
+			selfClosing := strings.HasSuffix(tag, "/")
+			name := strings.ToUpper(strings.Fields(strings.TrimSuffix(tag, "/"))[0])
+
+			n := &node{name: name}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			if !selfClosing {
+				stack = append(stack, n)
+			}
+		}
+	}
+
+	return root, nil
+}
@@ -0,0 +1,251 @@
+// Package payoff simulates paying down a set of debts month by month under
+// the avalanche, snowball or a custom ordering strategy. It works purely on
+// integer minor units and has no database dependencies.
+package payoff
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+type Strategy string
+
+const (
+	// Avalanche sends spare money to the debt with the highest APR first.
+	Avalanche Strategy = "avalanche"
+	// Snowball sends spare money to the debt with the smallest balance first.
+	Snowball Strategy = "snowball"
+	// Custom sends spare money to debts in an order chosen by the user.
+	Custom Strategy = "custom"
+)
+
+// maxMonths bounds the simulation; debts not paid off within 50 years are
+// treated as never being paid off.
+const maxMonths = 600
+
+var (
+	ErrInvalidPlan = errors.New("invalid payoff plan")
+	ErrNoPayoff    = errors.New("payments never pay off the debts")
+)
+
+// Debt is an amount owed. Balance is positive, APR is in basis points (1999
+// is 19.99%) and MinimumPayment is due every month until the debt is gone.
+type Debt struct {
+	ID             uint
+	Balance        int64
+	APR            int
+	MinimumPayment int64
+}
+
+// Payment is what happened to one debt in one month.
+type Payment struct {
+	DebtID    uint  `json:"debt_id"`
+	Interest  int64 `json:"interest"`
+	Payment   int64 `json:"payment"`
+	Principal int64 `json:"principal"`
+	Balance   int64 `json:"balance"`
+}
+
+type Month struct {
+	Date     time.Time `json:"date"`
+	Payments []Payment `json:"payments"`
+	Interest int64     `json:"interest"`
+	Paid     int64     `json:"paid"`
+}
+
+type DebtResult struct {
+	DebtID     uint      `json:"debt_id"`
+	Interest   int64     `json:"interest"`
+	Paid       int64     `json:"paid"`
+	Months     int       `json:"months"`
+	PayoffDate time.Time `json:"payoff_date"`
+}
+
+type Result struct {
+	Strategy   Strategy     `json:"strategy"`
+	Extra      int64        `json:"extra"`
+	Months     []Month      `json:"months"`
+	Debts      []DebtResult `json:"debts"`
+	Interest   int64        `json:"interest"`
+	Paid       int64        `json:"paid"`
+	PayoffDate time.Time    `json:"payoff_date"`
+}
+
+// Simulate pays the debts off from the month after start onwards. Every
+// month interest accrues on each balance, every debt gets its minimum
+// payment, and the rest of the monthly budget goes to debts in strategy
+// order. The budget stays at the sum of all minimums plus extra, so the
+// minimum of a paid-off debt rolls over to the next one. order lists debt ids
+// for Custom; debts it leaves out follow in avalanche order.
+//
+// Interest is balance × APR / 12 per month, rounded half up to a minor unit.
+func Simulate(debts []Debt, strategy Strategy, extra int64, order []uint, start time.Time) (Result, error) {
+	if extra < 0 {
+		return Result{}, ErrInvalidPlan
+	}
+
+	ranked, err := rank(debts, strategy, order)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Strategy: strategy,
+		Extra:    extra,
+		Months:   []Month{},
+		Debts:    make([]DebtResult, len(ranked)),
+	}
+
+	balances := make([]int64, len(ranked))
+	budget := extra
+	remaining := 0
+	for i, d := range ranked {
+		balances[i] = d.Balance
+		budget += d.MinimumPayment
+		result.Debts[i].DebtID = d.ID
+		if d.Balance > 0 {
+			remaining++
+		}
+	}
+
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for n := 1; remaining > 0; n++ {
+		if n > maxMonths {
+			return Result{}, ErrNoPayoff
+		}
+
+		month := Month{Date: first.AddDate(0, n, 0)}
+		payments := make([]Payment, len(ranked))
+		available := budget
+
+		for i, d := range ranked {
+			if balances[i] == 0 {
+				continue
+			}
+			interest := monthlyInterest(balances[i], d.APR)
+			balances[i] += interest
+			payments[i] = Payment{DebtID: d.ID, Interest: interest}
+
+			pay := min(d.MinimumPayment, balances[i])
+			payments[i].Payment = pay
+			balances[i] -= pay
+			available -= pay
+		}
+
+		for i := range ranked {
+			if available <= 0 {
+				break
+			}
+			pay := min(available, balances[i])
+			payments[i].Payment += pay
+			balances[i] -= pay
+			available -= pay
+		}
+
+		for i, p := range payments {
+			if p.DebtID == 0 {
+				continue // paid off in an earlier month
+			}
+			p.Principal = p.Payment - p.Interest
+			p.Balance = balances[i]
+			month.Payments = append(month.Payments, p)
+			month.Interest += p.Interest
+			month.Paid += p.Payment
+
+			dr := &result.Debts[i]
+			dr.Interest += p.Interest
+			dr.Paid += p.Payment
+			if p.Balance == 0 {
+				dr.Months = n
+				dr.PayoffDate = month.Date
+				remaining--
+			}
+		}
+
+		if month.Interest >= month.Paid {
+			// The whole budget went on interest, so the total owed can
+			// only grow from here.
+			return Result{}, ErrNoPayoff
+		}
+
+		result.Months = append(result.Months, month)
+		result.Interest += month.Interest
+		result.Paid += month.Paid
+		result.PayoffDate = month.Date
+	}
+
+	return result, nil
+}
+
+// rank validates debts and returns them in the order spare money is applied.
+func rank(debts []Debt, strategy Strategy, order []uint) ([]Debt, error) {
+	byID := make(map[uint]Debt, len(debts))
+	for _, d := range debts {
+		if d.ID == 0 || d.Balance < 0 || d.APR < 0 || d.MinimumPayment <= 0 {
+			return nil, ErrInvalidPlan
+		}
+		if _, dup := byID[d.ID]; dup {
+			return nil, ErrInvalidPlan
+		}
+		byID[d.ID] = d
+	}
+
+	ranked := make([]Debt, len(debts))
+	copy(ranked, debts)
+
+	avalanche := func(a, b Debt) bool {
+		if a.APR != b.APR {
+			return a.APR > b.APR
+		}
+		if a.Balance != b.Balance {
+			return a.Balance < b.Balance
+		}
+		return a.ID < b.ID
+	}
+
+	switch strategy {
+	case Avalanche:
+		sort.Slice(ranked, func(i, j int) bool { return avalanche(ranked[i], ranked[j]) })
+	case Snowball:
+		sort.Slice(ranked, func(i, j int) bool {
+			a, b := ranked[i], ranked[j]
+			if a.Balance != b.Balance {
+				return a.Balance < b.Balance
+			}
+			return avalanche(a, b)
+		})
+	case Custom:
+		position := make(map[uint]int, len(order))
+		for i, id := range order {
+			if _, ok := byID[id]; !ok {
+				return nil, ErrInvalidPlan
+			}
+			if _, dup := position[id]; dup {
+				return nil, ErrInvalidPlan
+			}
+			position[id] = i
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			a, b := ranked[i], ranked[j]
+			pa, aOK := position[a.ID]
+			pb, bOK := position[b.ID]
+			switch {
+			case aOK && bOK:
+				return pa < pb
+			case aOK != bOK:
+				return aOK
+			default:
+				return avalanche(a, b)
+			}
+		})
+	default:
+		return nil, ErrInvalidPlan
+	}
+
+	return ranked, nil
+}
+
+func monthlyInterest(balance int64, apr int) int64 {
+	return (balance*int64(apr) + 60_000) / 120_000
+}
@@ -0,0 +1,228 @@
+package payoff
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)
+
+func month(n int) time.Time {
+	return time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, n, 0)
+}
+
+func TestSimulate(t *testing.T) {
+	card := Debt{ID: 1, Balance: 1000, APR: 0, MinimumPayment: 100}
+	loan := Debt{ID: 2, Balance: 5000, APR: 2400, MinimumPayment: 100}
+
+	tests := []struct {
+		name     string
+		debts    []Debt
+		strategy Strategy
+		extra    int64
+		order    []uint
+
+		months   int
+		interest int64
+		paid     int64
+		// payoff is the month number each debt id is paid off in.
+		payoff map[uint]int
+		// first is each debt's balance after the first month.
+		first map[uint]int64
+	}{
+		{
+			name:     "single debt without interest",
+			debts:    []Debt{card},
+			strategy: Avalanche,
+			months:   10,
+			interest: 0,
+			paid:     1000,
+			payoff:   map[uint]int{1: 10},
+			first:    map[uint]int64{1: 900},
+		},
+		{
+			// 10000 at 1% a month: +100 -5000, +51 -5000, +1.51 rounds to 2.
+			name:     "single debt with interest",
+			debts:    []Debt{{ID: 1, Balance: 10000, APR: 1200, MinimumPayment: 5000}},
+			strategy: Avalanche,
+			months:   3,
+			interest: 153,
+			paid:     10153,
+			payoff:   map[uint]int{1: 3},
+			first:    map[uint]int64{1: 5100},
+		},
+		{
+			name:     "extra payment shortens the schedule",
+			debts:    []Debt{card},
+			strategy: Snowball,
+			extra:    150,
+			months:   4,
+			interest: 0,
+			paid:     1000,
+			payoff:   map[uint]int{1: 4},
+			first:    map[uint]int64{1: 750},
+		},
+		{
+			// The loan accrues 100 in the first month and gets its minimum
+			// plus the whole extra.
+			name:     "avalanche pays the highest rate first",
+			debts:    []Debt{card, loan},
+			strategy: Avalanche,
+			extra:    400,
+			first:    map[uint]int64{1: 900, 2: 4600},
+		},
+		{
+			name:     "snowball pays the smallest balance first",
+			debts:    []Debt{card, loan},
+			strategy: Snowball,
+			extra:    400,
+			payoff:   map[uint]int{1: 2},
+			first:    map[uint]int64{1: 500, 2: 5000},
+		},
+		{
+			name:     "custom order is followed",
+			debts:    []Debt{card, loan},
+			strategy: Custom,
+			extra:    400,
+			order:    []uint{1},
+			payoff:   map[uint]int{1: 2},
+			first:    map[uint]int64{1: 500, 2: 5000},
+		},
+		{
+			name:     "zero balance debt still frees its minimum",
+			debts:    []Debt{{ID: 1, Balance: 0, APR: 0, MinimumPayment: 100}, {ID: 2, Balance: 400, APR: 0, MinimumPayment: 100}},
+			strategy: Avalanche,
+			months:   2,
+			paid:     400,
+			payoff:   map[uint]int{2: 2},
+			first:    map[uint]int64{2: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Simulate(tt.debts, tt.strategy, tt.extra, tt.order, start)
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+
+			if tt.months != 0 && len(got.Months) != tt.months {
+				t.Errorf("months = %d, want %d", len(got.Months), tt.months)
+			}
+			if tt.months != 0 && got.Interest != tt.interest {
+				t.Errorf("interest = %d, want %d", got.Interest, tt.interest)
+			}
+			if tt.paid != 0 && got.Paid != tt.paid {
+				t.Errorf("paid = %d, want %d", got.Paid, tt.paid)
+			}
+			if !got.PayoffDate.Equal(month(len(got.Months))) {
+				t.Errorf("payoff date = %v, want %v", got.PayoffDate, month(len(got.Months)))
+			}
+
+			for _, d := range got.Debts {
+				want, ok := tt.payoff[d.DebtID]
+				if ok && d.Months != want {
+					t.Errorf("debt %d paid off in month %d, want %d", d.DebtID, d.Months, want)
+				}
+			}
+
+			for _, p := range got.Months[0].Payments {
+				want, ok := tt.first[p.DebtID]
+				if !ok {
+					t.Errorf("unexpected payment to debt %d", p.DebtID)
+					continue
+				}
+				if p.Balance != want {
+					t.Errorf("debt %d balance after first month = %d, want %d", p.DebtID, p.Balance, want)
+				}
+				if p.Principal != p.Payment-p.Interest {
+					t.Errorf("debt %d principal = %d, want payment - interest", p.DebtID, p.Principal)
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateAvalancheBeatsSnowball(t *testing.T) {
+	debts := []Debt{
+		{ID: 1, Balance: 100000, APR: 500, MinimumPayment: 2000},
+		{ID: 2, Balance: 300000, APR: 2499, MinimumPayment: 6000},
+	}
+
+	avalanche, err := Simulate(debts, Avalanche, 10000, nil, start)
+	if err != nil {
+		t.Fatalf("avalanche error = %v", err)
+	}
+	snowball, err := Simulate(debts, Snowball, 10000, nil, start)
+	if err != nil {
+		t.Fatalf("snowball error = %v", err)
+	}
+
+	if avalanche.Interest >= snowball.Interest {
+		t.Errorf("avalanche interest %d should be below snowball interest %d", avalanche.Interest, snowball.Interest)
+	}
+	for _, r := range []Result{avalanche, snowball} {
+		var owed int64
+		for _, d := range debts {
+			owed += d.Balance
+		}
+		if r.Paid != owed+r.Interest {
+			t.Errorf("%s paid %d, want balances plus interest %d", r.Strategy, r.Paid, owed+r.Interest)
+		}
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		debts    []Debt
+		strategy Strategy
+		extra    int64
+		order    []uint
+		want     error
+	}{
+		{
+			// 2% a month on 100000 is 2000, more than the 1000 minimum.
+			name:     "minimum below interest",
+			debts:    []Debt{{ID: 1, Balance: 100000, APR: 2400, MinimumPayment: 1000}},
+			strategy: Avalanche,
+			want:     ErrNoPayoff,
+		},
+		{
+			name:     "unknown strategy",
+			debts:    []Debt{{ID: 1, Balance: 100, MinimumPayment: 10}},
+			strategy: "fastest",
+			want:     ErrInvalidPlan,
+		},
+		{
+			name:     "custom order names an unknown debt",
+			debts:    []Debt{{ID: 1, Balance: 100, MinimumPayment: 10}},
+			strategy: Custom,
+			order:    []uint{2},
+			want:     ErrInvalidPlan,
+		},
+		{
+			name:     "missing minimum payment",
+			debts:    []Debt{{ID: 1, Balance: 100}},
+			strategy: Snowball,
+			want:     ErrInvalidPlan,
+		},
+		{
+			name:     "negative extra",
+			debts:    []Debt{{ID: 1, Balance: 100, MinimumPayment: 10}},
+			strategy: Snowball,
+			extra:    -1,
+			want:     ErrInvalidPlan,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Simulate(tt.debts, tt.strategy, tt.extra, tt.order, start)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Simulate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
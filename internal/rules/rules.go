@@ -0,0 +1,196 @@
+// Package rules evaluates a user's auto-categorisation rules against
+// transactions. It only decides what should change; persisting the result is
+// left to the caller.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Engine holds a set of rules ordered by priority with their regular
+// expressions compiled once.
+type Engine struct {
+	rules []compiled
+}
+
+type compiled struct {
+	model.Rule
+	re *regexp.Regexp
+}
+
+// Outcome describes the changes a set of rules makes to one transaction.
+type Outcome struct {
+	CategoryID   *uint    `json:"category_id,omitempty"`
+	Payee        *string  `json:"payee,omitempty"`
+	AddTags      []string `json:"add_tags,omitempty"`
+	MarkTransfer bool     `json:"mark_transfer,omitempty"`
+	RuleIDs      []uint   `json:"rule_ids"`
+}
+
+// Matched reports whether any rule fired.
+func (o Outcome) Matched() bool {
+	return len(o.RuleIDs) > 0
+}
+
+// Validate checks that r has at least one condition and one action and that
+// its payee pattern can be used.
+func Validate(r model.Rule) error {
+	_, err := compile(r)
+	return err
+}
+
+func compile(r model.Rule) (compiled, error) {
+	c := compiled{Rule: r}
+
+	hasCondition := r.PayeeMatch != "" || r.MinAmount != nil || r.MaxAmount != nil ||
+		r.AccountID != nil || r.MemoContains != ""
+	hasAction := r.SetCategoryID != nil || r.RenamePayee != "" || len(r.AddTags) > 0 || r.MarkTransfer
+	if !hasCondition || !hasAction {
+		return c, fmt.Errorf("%w: a rule needs at least one condition and one action", ErrInvalidRule)
+	}
+
+	switch r.PayeeMatch {
+	case "":
+	case model.PayeeMatchContains, model.PayeeMatchExact:
+		if r.PayeePattern == "" {
+			return c, fmt.Errorf("%w: payee_pattern is required", ErrInvalidRule)
+		}
+	case model.PayeeMatchRegex:
+		re, err := regexp.Compile(r.PayeePattern)
+		if err != nil {
+			return c, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		c.re = re
+	default:
+		return c, fmt.Errorf("%w: unknown payee_match %q", ErrInvalidRule, r.PayeeMatch)
+	}
+
+	if r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount {
+		return c, fmt.Errorf("%w: min_amount is greater than max_amount", ErrInvalidRule)
+	}
+
+	return c, nil
+}
+
+// New compiles the enabled rules in rs. Rules that fail to compile are
+// skipped; they are rejected when saved, so this only guards against data
+// written by older versions.
+func New(rs []model.Rule) *Engine {
+	e := &Engine{}
+	for _, r := range rs {
+		if !r.Enabled {
+			continue
+		}
+		if c, err := compile(r); err == nil {
+			e.rules = append(e.rules, c)
+		}
+	}
+
+	sort.SliceStable(e.rules, func(i, j int) bool {
+		if e.rules[i].Priority != e.rules[j].Priority {
+			return e.rules[i].Priority < e.rules[j].Priority
+		}
+		return e.rules[i].ID < e.rules[j].ID
+	})
+
+	return e
+}
+
+// Evaluate runs every rule against t and returns what should change.
+// Conditions are always checked against t as given, not against the result
+// of earlier rules. Unless override is set, a category already on t is kept.
+func (e *Engine) Evaluate(t model.Transaction, override bool) Outcome {
+	out := Outcome{RuleIDs: []uint{}}
+	seenTags := map[string]bool{}
+
+	for _, r := range e.rules {
+		if !r.matches(t) {
+			continue
+		}
+		out.RuleIDs = append(out.RuleIDs, r.ID)
+
+		if r.SetCategoryID != nil && out.CategoryID == nil && (override || t.CategoryID == nil) {
+			id := *r.SetCategoryID
+			out.CategoryID = &id
+		}
+		if r.RenamePayee != "" && out.Payee == nil {
+			payee := r.RenamePayee
+			out.Payee = &payee
+		}
+		for _, tag := range r.AddTags {
+			key := strings.ToLower(tag)
+			if !seenTags[key] {
+				seenTags[key] = true
+				out.AddTags = append(out.AddTags, tag)
+			}
+		}
+		if r.MarkTransfer {
+			out.MarkTransfer = true
+		}
+	}
+
+	return out
+}
+
+// Apply evaluates the rules and writes the outcome onto t. Tags are returned
+// rather than applied because they live in a separate table.
+func (e *Engine) Apply(t *model.Transaction, override bool) Outcome {
+	out := e.Evaluate(*t, override)
+
+	if out.CategoryID != nil {
+		t.CategoryID = out.CategoryID
+	}
+	if out.Payee != nil {
+		t.Payee = *out.Payee
+	}
+	if out.MarkTransfer {
+		t.IsTransfer = true
+		t.CategoryID = nil
+	}
+
+	return out
+}
+
+func (r compiled) matches(t model.Transaction) bool {
+	if t.TransferPeerID != nil {
+		return false
+	}
+
+	switch r.PayeeMatch {
+	case model.PayeeMatchContains:
+		if !strings.Contains(strings.ToLower(t.Payee), strings.ToLower(r.PayeePattern)) {
+			return false
+		}
+	case model.PayeeMatchExact:
+		if !strings.EqualFold(strings.TrimSpace(t.Payee), strings.TrimSpace(r.PayeePattern)) {
+			return false
+		}
+	case model.PayeeMatchRegex:
+		if !r.re.MatchString(t.Payee) {
+			return false
+		}
+	}
+
+	if r.MinAmount != nil && t.Amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && t.Amount > *r.MaxAmount {
+		return false
+	}
+	if r.AccountID != nil && t.AccountID != *r.AccountID {
+		return false
+	}
+	if r.MemoContains != "" && !strings.Contains(strings.ToLower(t.Memo), strings.ToLower(r.MemoContains)) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,231 @@
+package rules
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+func id(n uint) *uint { return &n }
+
+func amount(n int64) *int64 { return &n }
+
+func rule(ruleID uint, r model.Rule) model.Rule {
+	r.Model = gorm.Model{ID: ruleID}
+	r.Enabled = true
+	return r
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule model.Rule
+		want error
+	}{
+		{"contains", model.Rule{PayeeMatch: model.PayeeMatchContains, PayeePattern: "coffee", SetCategoryID: id(1)}, nil},
+		{"regex", model.Rule{PayeeMatch: model.PayeeMatchRegex, PayeePattern: `^UBER\s`, AddTags: []string{"travel"}}, nil},
+		{"amount only", model.Rule{MinAmount: amount(-500), MaxAmount: amount(-100), MarkTransfer: true}, nil},
+		{"no condition", model.Rule{SetCategoryID: id(1)}, ErrInvalidRule},
+		{"no action", model.Rule{PayeeMatch: model.PayeeMatchExact, PayeePattern: "Rent"}, ErrInvalidRule},
+		{"missing pattern", model.Rule{PayeeMatch: model.PayeeMatchContains, SetCategoryID: id(1)}, ErrInvalidRule},
+		{"bad regex", model.Rule{PayeeMatch: model.PayeeMatchRegex, PayeePattern: "(", SetCategoryID: id(1)}, ErrInvalidRule},
+		{"unknown match", model.Rule{PayeeMatch: "fuzzy", PayeePattern: "x", SetCategoryID: id(1)}, ErrInvalidRule},
+		{"inverted range", model.Rule{MinAmount: amount(100), MaxAmount: amount(-100), SetCategoryID: id(1)}, ErrInvalidRule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.rule); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	txn := model.Transaction{AccountID: 1, Amount: -450, Payee: "STARBUCKS #1234 Seattle", Memo: "Card purchase"}
+
+	tests := []struct {
+		name string
+		rule model.Rule
+		want bool
+	}{
+		{"contains ignores case", model.Rule{PayeeMatch: model.PayeeMatchContains, PayeePattern: "starbucks"}, true},
+		{"contains misses", model.Rule{PayeeMatch: model.PayeeMatchContains, PayeePattern: "costa"}, false},
+		{"exact ignores case and spacing", model.Rule{PayeeMatch: model.PayeeMatchExact, PayeePattern: " starbucks #1234 seattle "}, true},
+		{"exact needs the whole payee", model.Rule{PayeeMatch: model.PayeeMatchExact, PayeePattern: "Starbucks"}, false},
+		{"regex", model.Rule{PayeeMatch: model.PayeeMatchRegex, PayeePattern: `^STARBUCKS #\d+`}, true},
+		{"regex is case sensitive", model.Rule{PayeeMatch: model.PayeeMatchRegex, PayeePattern: `^starbucks`}, false},
+		{"regex with flag", model.Rule{PayeeMatch: model.PayeeMatchRegex, PayeePattern: `(?i)^starbucks`}, true},
+		{"within range", model.Rule{MinAmount: amount(-500), MaxAmount: amount(-400)}, true},
+		{"range bounds are inclusive", model.Rule{MinAmount: amount(-450), MaxAmount: amount(-450)}, true},
+		{"below minimum", model.Rule{MinAmount: amount(-400)}, false},
+		{"above maximum", model.Rule{MaxAmount: amount(-500)}, false},
+		{"account", model.Rule{AccountID: id(1)}, true},
+		{"other account", model.Rule{AccountID: id(2)}, false},
+		{"memo", model.Rule{MemoContains: "PURCHASE"}, true},
+		{"all conditions", model.Rule{PayeeMatch: model.PayeeMatchContains, PayeePattern: "starbucks", MaxAmount: amount(0), MemoContains: "refund"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.SetCategoryID = id(1)
+			c, err := compile(tt.rule)
+			if err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			if got := c.matches(txn); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	coffee := rule(1, model.Rule{
+		Priority: 10, PayeeMatch: model.PayeeMatchContains, PayeePattern: "starbucks",
+		SetCategoryID: id(5), RenamePayee: "Starbucks", AddTags: []string{"Coffee"},
+	})
+	food := rule(2, model.Rule{
+		Priority: 20, MaxAmount: amount(0),
+		SetCategoryID: id(6), RenamePayee: "Food", AddTags: []string{"coffee", "food"},
+	})
+	// Same priority as coffee but a higher id, so it runs after it.
+	treat := rule(3, model.Rule{
+		Priority: 10, MemoContains: "card",
+		SetCategoryID: id(7),
+	})
+	transfer := rule(4, model.Rule{
+		PayeeMatch: model.PayeeMatchExact, PayeePattern: "Transfer to savings",
+		MarkTransfer: true,
+	})
+	disabled := rule(5, model.Rule{PayeeMatch: model.PayeeMatchContains, PayeePattern: "starbucks", SetCategoryID: id(9)})
+	disabled.Enabled = false
+
+	tests := []struct {
+		name     string
+		rules    []model.Rule
+		txn      model.Transaction
+		override bool
+
+		ruleIDs  []uint
+		category *uint
+		payee    *string
+		tags     []string
+		transfer bool
+	}{
+		{
+			name:    "no rule matches",
+			rules:   []model.Rule{coffee},
+			txn:     model.Transaction{Payee: "Shell", Amount: -3000},
+			ruleIDs: []uint{},
+		},
+		{
+			name:     "first rule by priority wins",
+			rules:    []model.Rule{food, treat, coffee},
+			txn:      model.Transaction{Payee: "STARBUCKS 123", Amount: -450, Memo: "Card"},
+			ruleIDs:  []uint{1, 3, 2},
+			category: id(5),
+			payee:    ptr("Starbucks"),
+			tags:     []string{"Coffee", "food"},
+		},
+		{
+			name:    "existing category is kept",
+			rules:   []model.Rule{coffee},
+			txn:     model.Transaction{Payee: "Starbucks", Amount: -450, CategoryID: id(8)},
+			ruleIDs: []uint{1},
+			payee:   ptr("Starbucks"),
+			tags:    []string{"Coffee"},
+		},
+		{
+			name:     "override replaces the category",
+			rules:    []model.Rule{coffee},
+			txn:      model.Transaction{Payee: "Starbucks", Amount: -450, CategoryID: id(8)},
+			override: true,
+			ruleIDs:  []uint{1},
+			category: id(5),
+			payee:    ptr("Starbucks"),
+			tags:     []string{"Coffee"},
+		},
+		{
+			name:    "disabled rules are skipped",
+			rules:   []model.Rule{disabled},
+			txn:     model.Transaction{Payee: "Starbucks", Amount: -450},
+			ruleIDs: []uint{},
+		},
+		{
+			name:     "mark as transfer",
+			rules:    []model.Rule{transfer},
+			txn:      model.Transaction{Payee: "transfer to Savings", Amount: -10000},
+			ruleIDs:  []uint{4},
+			transfer: true,
+		},
+		{
+			name:    "transfer legs are never matched",
+			rules:   []model.Rule{coffee},
+			txn:     model.Transaction{Payee: "Starbucks", Amount: -450, TransferPeerID: id(12)},
+			ruleIDs: []uint{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := New(tt.rules).Evaluate(tt.txn, tt.override)
+
+			if !slices.Equal(out.RuleIDs, tt.ruleIDs) {
+				t.Errorf("RuleIDs = %v, want %v", out.RuleIDs, tt.ruleIDs)
+			}
+			if out.Matched() != (len(tt.ruleIDs) > 0) {
+				t.Errorf("Matched() = %v", out.Matched())
+			}
+			if !samePtr(out.CategoryID, tt.category) {
+				t.Errorf("CategoryID = %v, want %v", deref(out.CategoryID), deref(tt.category))
+			}
+			if !samePtr(out.Payee, tt.payee) {
+				t.Errorf("Payee = %v, want %v", deref(out.Payee), deref(tt.payee))
+			}
+			if !slices.Equal(out.AddTags, tt.tags) {
+				t.Errorf("AddTags = %v, want %v", out.AddTags, tt.tags)
+			}
+			if out.MarkTransfer != tt.transfer {
+				t.Errorf("MarkTransfer = %v, want %v", out.MarkTransfer, tt.transfer)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	engine := New([]model.Rule{
+		rule(1, model.Rule{PayeeMatch: model.PayeeMatchContains, PayeePattern: "savings", MarkTransfer: true}),
+		rule(2, model.Rule{MaxAmount: amount(0), SetCategoryID: id(5), RenamePayee: "Moved"}),
+	})
+
+	txn := model.Transaction{Payee: "To savings", Amount: -10000}
+	engine.Apply(&txn, false)
+
+	if !txn.IsTransfer || txn.CategoryID != nil {
+		t.Errorf("Apply() IsTransfer = %v, CategoryID = %v, want a transfer without category", txn.IsTransfer, deref(txn.CategoryID))
+	}
+	if txn.Payee != "Moved" {
+		t.Errorf("Apply() Payee = %q, want %q", txn.Payee, "Moved")
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func samePtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
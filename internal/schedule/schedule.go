@@ -0,0 +1,142 @@
+// Package schedule expands recurrence rules, a small subset of iCalendar
+// RRULE, into concrete calendar dates.
+package schedule
+
+import (
+	"errors"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+var ErrInvalidRule = errors.New("invalid schedule")
+
+// maxOccurrences bounds how many dates a single call will produce so a
+// daily rule queried over a long range cannot run away.
+const maxOccurrences = 1000
+
+// Rule describes when something repeats. It occurs every Interval units of
+// Frequency counted from Start, and never after Until when that is set.
+//
+// For monthly and yearly rules the day of the month is taken from
+// ByMonthDay, or from Start when it is 0; -1 means the last day of the month,
+// and days that do not exist in a month (e.g. the 31st) fall on its last day.
+// LastBusinessDay instead picks the last Monday to Friday of the month.
+type Rule struct {
+	Frequency       Frequency
+	Interval        int
+	Start           time.Time
+	Until           *time.Time
+	ByMonthDay      int
+	LastBusinessDay bool
+}
+
+// Validate reports whether r can be expanded.
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return ErrInvalidRule
+	}
+
+	if r.Interval < 1 || r.Start.IsZero() {
+		return ErrInvalidRule
+	}
+	if r.ByMonthDay < -1 || r.ByMonthDay > 31 {
+		return ErrInvalidRule
+	}
+	if r.LastBusinessDay && r.Frequency != Monthly {
+		return ErrInvalidRule
+	}
+	if r.Until != nil && r.Until.Before(day(r.Start)) {
+		return ErrInvalidRule
+	}
+
+	return nil
+}
+
+// Between returns the dates in [from, to] on which r occurs, in order.
+func (r Rule) Between(from, to time.Time) []time.Time {
+	from, to = day(from), day(to)
+	if r.Until != nil && day(*r.Until).Before(to) {
+		to = day(*r.Until)
+	}
+
+	var dates []time.Time
+	for n := 0; len(dates) < maxOccurrences; n++ {
+		d := r.nth(n)
+		if d.After(to) {
+			break
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+
+	return dates
+}
+
+// Next returns the first date on or after from on which r occurs.
+func (r Rule) Next(from time.Time) (time.Time, bool) {
+	from = day(from)
+	for n := 0; ; n++ {
+		d := r.nth(n)
+		if r.Until != nil && d.After(day(*r.Until)) {
+			return time.Time{}, false
+		}
+		if !d.Before(from) {
+			return d, true
+		}
+	}
+}
+
+// nth returns the date of the n-th occurrence, counting from zero.
+func (r Rule) nth(n int) time.Time {
+	start := day(r.Start)
+	step := n * r.Interval
+
+	switch r.Frequency {
+	case Daily:
+		return start.AddDate(0, 0, step)
+	case Weekly:
+		return start.AddDate(0, 0, 7*step)
+	case Yearly:
+		return r.inMonth(start.Year()+step, start.Month())
+	default:
+		months := int(start.Month()) - 1 + step
+		return r.inMonth(start.Year()+months/12, time.Month(months%12+1))
+	}
+}
+
+func (r Rule) inMonth(year int, month time.Month) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+
+	if r.LastBusinessDay {
+		for last.Weekday() == time.Saturday || last.Weekday() == time.Sunday {
+			last = last.AddDate(0, 0, -1)
+		}
+		return last
+	}
+
+	dom := r.ByMonthDay
+	if dom == 0 {
+		dom = r.Start.Day()
+	}
+	if dom == -1 || dom > last.Day() {
+		return last
+	}
+
+	return time.Date(year, month, dom, 0, 0, 0, 0, time.UTC)
+}
+
+// day truncates t to midnight UTC of its calendar date.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
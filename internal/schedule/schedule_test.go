@@ -0,0 +1,199 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func datePtr(s string) *time.Time {
+	t := date(s)
+	return &t
+}
+
+func TestValidate(t *testing.T) {
+	start := date("2026-01-15")
+
+	tests := []struct {
+		name string
+		rule Rule
+		want error
+	}{
+		{"monthly", Rule{Frequency: Monthly, Interval: 1, Start: start}, nil},
+		{"last day of month", Rule{Frequency: Yearly, Interval: 1, Start: start, ByMonthDay: -1}, nil},
+		{"last business day", Rule{Frequency: Monthly, Interval: 1, Start: start, LastBusinessDay: true}, nil},
+		{"until on start", Rule{Frequency: Daily, Interval: 1, Start: start, Until: datePtr("2026-01-15")}, nil},
+		{"unknown frequency", Rule{Frequency: "hourly", Interval: 1, Start: start}, ErrInvalidRule},
+		{"zero interval", Rule{Frequency: Daily, Start: start}, ErrInvalidRule},
+		{"missing start", Rule{Frequency: Daily, Interval: 1}, ErrInvalidRule},
+		{"day of month too large", Rule{Frequency: Monthly, Interval: 1, Start: start, ByMonthDay: 32}, ErrInvalidRule},
+		{"day of month too small", Rule{Frequency: Monthly, Interval: 1, Start: start, ByMonthDay: -2}, ErrInvalidRule},
+		{"last business day weekly", Rule{Frequency: Weekly, Interval: 1, Start: start, LastBusinessDay: true}, ErrInvalidRule},
+		{"until before start", Rule{Frequency: Daily, Interval: 1, Start: start, Until: datePtr("2026-01-14")}, ErrInvalidRule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		from, to string
+		want     []string
+	}{
+		{
+			name: "daily every third day until",
+			rule: Rule{Frequency: Daily, Interval: 3, Start: date("2026-01-01"), Until: datePtr("2026-01-10")},
+			from: "2026-01-01", to: "2026-02-01",
+			want: []string{"2026-01-01", "2026-01-04", "2026-01-07", "2026-01-10"},
+		},
+		{
+			name: "fortnightly",
+			rule: Rule{Frequency: Weekly, Interval: 2, Start: date("2026-01-05")},
+			from: "2026-01-01", to: "2026-02-02",
+			want: []string{"2026-01-05", "2026-01-19", "2026-02-02"},
+		},
+		{
+			name: "monthly from the middle of the schedule",
+			rule: Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-15")},
+			from: "2026-03-01", to: "2026-05-15",
+			want: []string{"2026-03-15", "2026-04-15", "2026-05-15"},
+		},
+		{
+			name: "monthly on the 31st falls on short months' last day",
+			rule: Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-31")},
+			from: "2026-01-01", to: "2026-04-30",
+			want: []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"},
+		},
+		{
+			name: "last day of month",
+			rule: Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-10"), ByMonthDay: -1},
+			from: "2026-01-01", to: "2026-03-31",
+			want: []string{"2026-01-31", "2026-02-28", "2026-03-31"},
+		},
+		{
+			name: "last business day skips weekends",
+			rule: Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-01"), LastBusinessDay: true},
+			from: "2026-01-01", to: "2026-03-31",
+			want: []string{"2026-01-30", "2026-02-27", "2026-03-31"},
+		},
+		{
+			name: "quarterly",
+			rule: Rule{Frequency: Monthly, Interval: 3, Start: date("2025-11-01")},
+			from: "2026-01-01", to: "2026-12-31",
+			want: []string{"2026-02-01", "2026-05-01", "2026-08-01", "2026-11-01"},
+		},
+		{
+			name: "yearly on a leap day",
+			rule: Rule{Frequency: Yearly, Interval: 1, Start: date("2024-02-29")},
+			from: "2025-01-01", to: "2028-12-31",
+			want: []string{"2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"},
+		},
+		{
+			name: "range before start",
+			rule: Rule{Frequency: Daily, Interval: 1, Start: date("2026-06-01")},
+			from: "2026-01-01", to: "2026-05-31",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Between(date(tt.from), date(tt.to))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Between() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(date(tt.want[i])) {
+					t.Errorf("Between()[%d] = %s, want %s", i, got[i].Format(time.DateOnly), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBetweenIsBounded(t *testing.T) {
+	rule := Rule{Frequency: Daily, Interval: 1, Start: date("2000-01-01")}
+
+	if got := rule.Between(date("2000-01-01"), date("2100-01-01")); len(got) != maxOccurrences {
+		t.Errorf("Between() returned %d dates, want %d", len(got), maxOccurrences)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		from   time.Time
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "on an occurrence",
+			rule:   Rule{Frequency: Weekly, Interval: 1, Start: date("2026-01-05")},
+			from:   date("2026-01-12"),
+			want:   "2026-01-12",
+			wantOK: true,
+		},
+		{
+			name:   "between occurrences",
+			rule:   Rule{Frequency: Weekly, Interval: 1, Start: date("2026-01-05")},
+			from:   date("2026-01-13"),
+			want:   "2026-01-19",
+			wantOK: true,
+		},
+		{
+			name:   "before start",
+			rule:   Rule{Frequency: Monthly, Interval: 1, Start: date("2026-03-10")},
+			from:   date("2026-01-01"),
+			want:   "2026-03-10",
+			wantOK: true,
+		},
+		{
+			name:   "time of day is ignored",
+			rule:   Rule{Frequency: Daily, Interval: 2, Start: date("2026-01-01")},
+			from:   time.Date(2026, time.January, 3, 23, 59, 0, 0, time.UTC),
+			want:   "2026-01-03",
+			wantOK: true,
+		},
+		{
+			name:   "after until",
+			rule:   Rule{Frequency: Daily, Interval: 3, Start: date("2026-01-01"), Until: datePtr("2026-01-10")},
+			from:   date("2026-01-11"),
+			wantOK: false,
+		},
+		{
+			name:   "next one would be after until",
+			rule:   Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-20"), Until: datePtr("2026-03-01")},
+			from:   date("2026-02-21"),
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rule.Next(tt.from)
+			if ok != tt.wantOK {
+				t.Fatalf("Next() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !got.Equal(date(tt.want)) {
+				t.Errorf("Next() = %s, want %s", got.Format(time.DateOnly), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Package storage keeps uploaded files, such as receipts, outside the
+// database. A Bucket stores opaque blobs under slash-separated keys chosen by
+// the caller; what a blob is and who may read it is tracked elsewhere.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Bucket is a place blobs can be written to, read back from and removed
+// from. The local filesystem is the only implementation today; an
+// S3-compatible one can be added behind the same interface.
+type Bucket interface {
+	// Put stores everything read from r under key, replacing any blob
+	// already there, and returns the number of bytes written.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns a reader for the blob under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey rejects keys that are empty, absolute or that would step outside
+// the bucket.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return ErrInvalidKey
+	}
+	return nil
+}
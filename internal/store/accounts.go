@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+// ErrAccountInUse is returned when deleting an account that still has
+// transactions, goals or debts. It is a conflict.
+var ErrAccountInUse = fmt.Errorf("%w: account still has transactions, goals or debts", ErrConflict)
+
+type AccountsStorage struct {
+	db *gorm.DB
+}
+
+func (s *AccountsStorage) Create(ctx context.Context, account *model.Account) error {
+	// A new account has no transactions yet, so its balance is the opening balance.
+	account.Balance = account.OpeningBalance
+
+	return s.db.WithContext(ctx).Create(account).Error
+}
+
+func (s *AccountsStorage) GetByID(ctx context.Context, userID, accountID uint) (*model.Account, error) {
+	var account model.Account
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", accountID, userID).
+		First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (s *AccountsStorage) List(ctx context.Context, userID uint) ([]model.Account, error) {
+	accounts := []model.Account{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("name, id").
+		Find(&accounts).Error
+
+	return accounts, err
+}
+
+// Update persists the editable fields of an account. Changing the opening
+// balance shifts the current balance by the same delta so the two stay in step.
+func (s *AccountsStorage) Update(ctx context.Context, account *model.Account) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Account
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", account.ID, account.UserID).
+			First(&current).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		account.Balance = current.Balance + account.OpeningBalance - current.OpeningBalance
+
+		return tx.Model(&current).Select("name", "type", "opening_balance", "balance", "low_balance_threshold").Updates(account).Error
+	})
+}
+
+// Delete removes an account and ends the schedules of the recurring
+// transactions posting to it. An account that still has transactions, which
+// includes legs of transfers, cannot be deleted: they would linger in lists
+// and reports, and their transfer peers would point at nothing. The same goes
+// for goals and debts, which would keep tracking a deleted balance.
+func (s *AccountsStorage) Delete(ctx context.Context, userID, accountID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var account model.Account
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", accountID, userID).
+			First(&account).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		for _, m := range []any{&model.Transaction{}, &model.Goal{}, &model.Debt{}} {
+			var count int64
+			if err := tx.Model(m).Where("account_id = ?", accountID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrAccountInUse
+			}
+		}
+
+		if err := tx.Delete(&account).Error; err != nil {
+			return err
+		}
+
+		return endSchedule(tx, "account_id = ? AND user_id = ?", accountID, userID)
+	})
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+type AttachmentsStorage struct {
+	db *gorm.DB
+}
+
+// Create records an uploaded attachment, failing with ErrQuotaExceeded when
+// it would take the user's attachments over quota bytes in total. The user's
+// row is locked so concurrent uploads cannot both slip under the quota.
+func (s *AttachmentsStorage) Create(ctx context.Context, a *model.Attachment, quota int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Clauses(lockForUpdate).Select("id").First(&user, a.UserID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		err := tx.Model(&model.Transaction{}).
+			Where("id = ? AND user_id = ?", a.TransactionID, a.UserID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		used, err := attachmentUsage(tx, a.UserID)
+		if err != nil {
+			return err
+		}
+		if used+a.Size > quota {
+			return ErrQuotaExceeded
+		}
+
+		return tx.Create(a).Error
+	})
+}
+
+func (s *AttachmentsStorage) GetByID(ctx context.Context, userID, transactionID, attachmentID uint) (*model.Attachment, error) {
+	var a model.Attachment
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND transaction_id = ?", attachmentID, userID, transactionID).
+		First(&a).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (s *AttachmentsStorage) List(ctx context.Context, userID, transactionID uint) ([]model.Attachment, error) {
+	attachments := []model.Attachment{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_id = ?", userID, transactionID).
+		Order("id").
+		Find(&attachments).Error
+
+	return attachments, err
+}
+
+func (s *AttachmentsStorage) Delete(ctx context.Context, userID, attachmentID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", attachmentID, userID).
+		Delete(&model.Attachment{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Usage returns how many bytes the user's attachments take up.
+func (s *AttachmentsStorage) Usage(ctx context.Context, userID uint) (int64, error) {
+	return attachmentUsage(s.db.WithContext(ctx), userID)
+}
+
+func attachmentUsage(db *gorm.DB, userID uint) (int64, error) {
+	var used int64
+	err := db.Model(&model.Attachment{}).
+		Select("COALESCE(SUM(size), 0)").
+		Where("user_id = ?", userID).
+		Scan(&used).Error
+
+	return used, err
+}
+
+// deleteAttachments removes the attachment records of the given transactions
+// and returns the keys of their blobs.
+func deleteAttachments(tx *gorm.DB, userID uint, transactionIDs []uint) ([]string, error) {
+	var keys []string
+	err := tx.Model(&model.Attachment{}).
+		Where("user_id = ? AND transaction_id IN ?", userID, transactionIDs).
+		Pluck("key", &keys).Error
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	err = tx.Where("user_id = ? AND transaction_id IN ?", userID, transactionIDs).
+		Delete(&model.Attachment{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
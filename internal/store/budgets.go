@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/budget"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+// BudgetStatus is the computed status of one budget in a given month.
+type BudgetStatus struct {
+	BudgetID   uint `json:"budget_id"`
+	CategoryID uint `json:"category_id"`
+	Rollover   bool `json:"rollover"`
+	budget.Status
+}
+
+type BudgetsStorage struct {
+	db *gorm.DB
+}
+
+func (s *BudgetsStorage) Create(ctx context.Context, b *model.Budget) error {
+	b.Month = budget.MonthStart(b.Month)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category model.Category
+		err := tx.Where("id = ? AND user_id = ?", b.CategoryID, b.UserID).First(&category).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if category.Kind != model.CategoryKindExpense {
+			return ErrInvalidCategory
+		}
+
+		var count int64
+		err = tx.Model(&model.Budget{}).
+			Where("user_id = ? AND category_id = ? AND month = ?", b.UserID, b.CategoryID, b.Month).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrConflict
+		}
+
+		return tx.Create(b).Error
+	})
+}
+
+func (s *BudgetsStorage) GetByID(ctx context.Context, userID, budgetID uint) (*model.Budget, error) {
+	var b model.Budget
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", budgetID, userID).
+		First(&b).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// List returns the user's budgets, limited to a single month when month is
+// not nil.
+func (s *BudgetsStorage) List(ctx context.Context, userID uint, month *time.Time) ([]model.Budget, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if month != nil {
+		query = query.Where("month = ?", budget.MonthStart(*month))
+	}
+
+	budgets := []model.Budget{}
+	err := query.Order("month DESC, category_id").Find(&budgets).Error
+
+	return budgets, err
+}
+
+func (s *BudgetsStorage) Update(ctx context.Context, b *model.Budget) error {
+	result := s.db.WithContext(ctx).
+		Model(b).
+		Where("user_id = ?", b.UserID).
+		Select("amount", "rollover").
+		Updates(b)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *BudgetsStorage) Delete(ctx context.Context, userID, budgetID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", budgetID, userID).
+		Delete(&model.Budget{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Statuses computes the status of every budget the user has for month,
+// ordered by category. The budgets of earlier months are loaded too so that
+// rollover amounts can be carried forward.
+func (s *BudgetsStorage) Statuses(ctx context.Context, userID uint, month time.Time) ([]BudgetStatus, error) {
+	month = budget.MonthStart(month)
+	db := s.db.WithContext(ctx)
+
+	var budgets []model.Budget
+	err := db.Where("user_id = ? AND month <= ?", userID, month).
+		Order("month").
+		Find(&budgets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []BudgetStatus{}
+	if len(budgets) == 0 {
+		return statuses, nil
+	}
+
+	var categories []model.Category
+	if err := db.Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]*uint, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+
+	conv, err := loadConverter(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	spending, err := monthlySpending(db, conv, userID, budgets[0].Month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	rolledUp := make(map[time.Time]map[uint]int64, len(spending))
+	for m, spent := range spending {
+		rolledUp[m] = budget.Rollup(spent, parents)
+	}
+
+	periods := map[uint][]budget.Period{}
+	current := map[uint]model.Budget{}
+	for _, b := range budgets {
+		m := budget.MonthStart(b.Month)
+		periods[b.CategoryID] = append(periods[b.CategoryID], budget.Period{
+			Month:    m,
+			Budgeted: b.Amount,
+			Spent:    rolledUp[m][b.CategoryID],
+			Rollover: b.Rollover,
+		})
+		if m.Equal(month) {
+			current[b.CategoryID] = b
+		}
+	}
+
+	for categoryID, b := range current {
+		computed := budget.Compute(periods[categoryID])
+		statuses = append(statuses, BudgetStatus{
+			BudgetID:   b.ID,
+			CategoryID: categoryID,
+			Rollover:   b.Rollover,
+			Status:     computed[len(computed)-1],
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].CategoryID < statuses[j].CategoryID })
+
+	return statuses, nil
+}
+
+// monthlySpending returns the net amount spent per category per month in
+// [from, to), keyed by the first day of the month and converted into the home
+// currency. Split transactions count line by line, refunds reduce spending
+// and transfers are ignored. Amounts already in the home currency are summed
+// per month in SQL; others are summed per day so each day can be converted at
+// its own rate.
+func monthlySpending(db *gorm.DB, conv *converter, userID uint, from, to time.Time) (map[time.Time]map[uint]int64, error) {
+	var rows []struct {
+		CategoryID uint
+		Currency   string
+		Date       time.Time
+		Spent      int64
+	}
+
+	err := db.Raw(`
+		SELECT t.category_id, a.currency,
+			CASE WHEN a.currency = ? THEN date_trunc('month', t.date)::date ELSE t.date END AS date,
+			-SUM(t.amount) AS spent
+		FROM `+ledgerLines+` t
+		JOIN accounts a ON a.id = t.account_id
+		WHERE t.user_id = ? AND t.category_id IS NOT NULL AND NOT t.is_transfer
+			AND t.date >= ? AND t.date < ?
+		GROUP BY 1, 2, 3`, conv.home, userID, from, to).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	spending := map[time.Time]map[uint]int64{}
+	for _, row := range rows {
+		spent, ok := conv.convert(row.Spent, row.Currency, row.Date)
+		if !ok {
+			continue
+		}
+
+		m := budget.MonthStart(row.Date)
+		if spending[m] == nil {
+			spending[m] = map[uint]int64{}
+		}
+		spending[m][row.CategoryID] += spent
+	}
+
+	return spending, nil
+}
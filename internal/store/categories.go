@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCategory = errors.New("invalid category")
+
+type defaultCategory struct {
+	name     string
+	kind     model.CategoryKind
+	children []string
+}
+
+// defaultCategories is the tree every new user starts with.
+var defaultCategories = []defaultCategory{
+	{"Salary", model.CategoryKindIncome, nil},
+	{"Bonus", model.CategoryKindIncome, nil},
+	{"Interest & Dividends", model.CategoryKindIncome, nil},
+	{"Other Income", model.CategoryKindIncome, nil},
+	{"Housing", model.CategoryKindExpense, []string{"Rent", "Mortgage", "Utilities", "Maintenance"}},
+	{"Food", model.CategoryKindExpense, []string{"Groceries", "Restaurants", "Coffee"}},
+	{"Transportation", model.CategoryKindExpense, []string{"Fuel", "Public Transit", "Parking", "Car Maintenance"}},
+	{"Health", model.CategoryKindExpense, []string{"Insurance", "Doctor", "Pharmacy"}},
+	{"Shopping", model.CategoryKindExpense, []string{"Clothing", "Household", "Electronics"}},
+	{"Entertainment", model.CategoryKindExpense, []string{"Subscriptions", "Hobbies", "Travel"}},
+	{"Personal Care", model.CategoryKindExpense, nil},
+	{"Education", model.CategoryKindExpense, nil},
+	{"Gifts & Donations", model.CategoryKindExpense, nil},
+	{"Fees & Charges", model.CategoryKindExpense, nil},
+}
+
+func seedDefaultCategories(tx *gorm.DB, userID uint) error {
+	for _, d := range defaultCategories {
+		parent := model.Category{UserID: userID, Name: d.name, Kind: d.kind}
+		if err := tx.Create(&parent).Error; err != nil {
+			return err
+		}
+
+		if len(d.children) == 0 {
+			continue
+		}
+
+		children := make([]model.Category, 0, len(d.children))
+		for _, name := range d.children {
+			children = append(children, model.Category{
+				UserID:   userID,
+				ParentID: &parent.ID,
+				Name:     name,
+				Kind:     d.kind,
+			})
+		}
+		if err := tx.Create(&children).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type CategoriesStorage struct {
+	db *gorm.DB
+}
+
+func (s *CategoriesStorage) Create(ctx context.Context, category *model.Category) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, category); err != nil {
+			return err
+		}
+
+		return tx.Create(category).Error
+	})
+}
+
+func (s *CategoriesStorage) GetByID(ctx context.Context, userID, categoryID uint) (*model.Category, error) {
+	var category model.Category
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", categoryID, userID).
+		First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (s *CategoriesStorage) List(ctx context.Context, userID uint) ([]model.Category, error) {
+	categories := []model.Category{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("kind, parent_id NULLS FIRST, name").
+		Find(&categories).Error
+
+	return categories, err
+}
+
+func (s *CategoriesStorage) Update(ctx context.Context, category *model.Category) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, category); err != nil {
+			return err
+		}
+
+		result := tx.Model(category).
+			Where("user_id = ?", category.UserID).
+			Select("name", "parent_id").
+			Updates(category)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// Delete removes a category after moving everything that referenced it onto
+// the replacement: its transactions, split lines, rules and recurring
+// transactions are recategorised, its budgets are moved or, for months the
+// replacement already has a budget for, added to the replacement's, and its
+// children are re-parented to the deleted category's own parent.
+func (s *CategoriesStorage) Delete(ctx context.Context, userID, categoryID, replacementID uint) error {
+	if categoryID == replacementID {
+		return ErrInvalidCategory
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category, replacement model.Category
+		if err := tx.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := tx.Where("id = ? AND user_id = ?", replacementID, userID).First(&replacement).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if replacement.Kind != category.Kind {
+			return ErrInvalidCategory
+		}
+
+		err := tx.Model(&model.Transaction{}).
+			Where("user_id = ? AND category_id = ?", userID, categoryID).
+			Update("category_id", replacementID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.TransactionSplit{}).
+			Where("category_id = ?", categoryID).
+			Update("category_id", replacementID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.Rule{}).
+			Where("user_id = ? AND set_category_id = ?", userID, categoryID).
+			Update("set_category_id", replacementID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.RecurringTransaction{}).
+			Where("user_id = ? AND category_id = ?", userID, categoryID).
+			Update("category_id", replacementID).Error
+		if err != nil {
+			return err
+		}
+
+		if err := moveBudgets(tx, userID, categoryID, replacementID); err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.Category{}).
+			Where("user_id = ? AND parent_id = ?", userID, categoryID).
+			Update("parent_id", category.ParentID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&category).Error
+	})
+}
+
+// moveBudgets hands the budgets of one category over to another, adding the
+// amounts together for months both have a budget for.
+func moveBudgets(tx *gorm.DB, userID, fromID, toID uint) error {
+	err := tx.Exec(`
+		UPDATE budgets AS r SET amount = r.amount + b.amount, updated_at = NOW()
+		FROM budgets AS b
+		WHERE r.user_id = ? AND r.category_id = ? AND r.deleted_at IS NULL
+			AND b.user_id = r.user_id AND b.category_id = ? AND b.deleted_at IS NULL
+			AND b.month = r.month`, userID, toID, fromID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("user_id = ? AND category_id = ?", userID, fromID).
+		Where("month IN (?)", tx.Model(&model.Budget{}).Select("month").Where("user_id = ? AND category_id = ?", userID, toID)).
+		Delete(&model.Budget{}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&model.Budget{}).
+		Where("user_id = ? AND category_id = ?", userID, fromID).
+		Update("category_id", toID).Error
+}
+
+// checkCategoryParent makes sure the parent of category belongs to the same
+// user, has the same kind and is not the category itself or one of its
+// descendants. A parent of 0 is cleared, making category a root category.
+func checkCategoryParent(tx *gorm.DB, category *model.Category) error {
+	if category.ParentID != nil && *category.ParentID == 0 {
+		category.ParentID = nil
+	}
+	if category.ParentID == nil {
+		return nil
+	}
+
+	var parent model.Category
+	err := tx.Where("id = ? AND user_id = ?", *category.ParentID, category.UserID).First(&parent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if parent.Kind != category.Kind {
+		return ErrInvalidCategory
+	}
+
+	if category.ID == 0 {
+		return nil
+	}
+
+	var cycle bool
+	err = tx.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, c.parent_id FROM categories c
+			JOIN ancestors a ON c.id = a.parent_id
+			WHERE c.deleted_at IS NULL
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = ?)`, parent.ID, category.ID).
+		Scan(&cycle).Error
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrInvalidCategory
+	}
+
+	return nil
+}
+
+// checkTransactionCategory makes sure a category assigned to a transaction
+// belongs to the same user.
+func checkTransactionCategory(tx *gorm.DB, userID uint, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	var count int64
+	err := tx.Model(&model.Category{}).
+		Where("id = ? AND user_id = ?", *categoryID, userID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
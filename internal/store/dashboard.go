@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/budget"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+const topCategoriesLimit = 5
+
+// DashboardSummary is what the dashboard shows for a user. Money figures
+// other than per-account balances are in HomeCurrency; MissingRates lists
+// currencies that could not be converted and were left out of them.
+type DashboardSummary struct {
+	HomeCurrency       string              `json:"home_currency"`
+	NetWorth           int64               `json:"net_worth"`
+	NetWorthByCurrency []CurrencyAmount    `json:"net_worth_by_currency"`
+	Accounts           []AccountBalance    `json:"accounts"`
+	CashFlow           CashFlow            `json:"cash_flow"`
+	TopCategories      []CategorySpending  `json:"top_categories"`
+	Budgets            BudgetHealth        `json:"budgets"`
+	RecentTransactions []model.Transaction `json:"recent_transactions"`
+	MissingRates       []string            `json:"missing_rates"`
+}
+
+type CurrencyAmount struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// AccountBalance is an account's balance in its own currency and, when a
+// rate is available, in the home currency.
+type AccountBalance struct {
+	ID          uint              `json:"id"`
+	Name        string            `json:"name"`
+	Type        model.AccountType `json:"type"`
+	Currency    string            `json:"currency"`
+	Balance     int64             `json:"balance"`
+	HomeBalance *int64            `json:"home_balance"`
+}
+
+// CashFlow is the income and expense of the current month. Both figures are
+// positive.
+type CashFlow struct {
+	Income  int64 `json:"income"`
+	Expense int64 `json:"expense"`
+}
+
+type CategorySpending struct {
+	CategoryID uint   `json:"category_id"`
+	Name       string `json:"name"`
+	Spent      int64  `json:"spent"`
+}
+
+type BudgetHealth struct {
+	Total     int            `json:"total"`
+	OnTrack   int            `json:"on_track"`
+	Overspent int            `json:"overspent"`
+	Items     []BudgetStatus `json:"items"`
+}
+
+type DashboardStorage struct {
+	db *gorm.DB
+}
+
+// Summary aggregates everything the dashboard shows for a user, converted into
+// their home currency. It issues a fixed number of queries regardless of how
+// many accounts, categories or budgets the user has.
+func (s *DashboardStorage) Summary(ctx context.Context, userID uint, now time.Time, recent int) (*DashboardSummary, error) {
+	db := s.db.WithContext(ctx)
+	from := budget.MonthStart(now)
+	to := from.AddDate(0, 1, 0)
+
+	conv, err := loadConverter(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &DashboardSummary{
+		HomeCurrency:       conv.home,
+		NetWorthByCurrency: []CurrencyAmount{},
+		Accounts:           []AccountBalance{},
+		TopCategories:      []CategorySpending{},
+		RecentTransactions: []model.Transaction{},
+	}
+
+	err = db.Model(&model.Account{}).
+		Select("id, name, type, currency, balance").
+		Where("user_id = ?", userID).
+		Order("name, id").
+		Scan(&summary.Accounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := map[string]int64{}
+	var currencies []string
+	for i, a := range summary.Accounts {
+		if _, ok := byCurrency[a.Currency]; !ok {
+			currencies = append(currencies, a.Currency)
+		}
+		byCurrency[a.Currency] += a.Balance
+
+		if home, ok := conv.convert(a.Balance, a.Currency, now); ok {
+			summary.Accounts[i].HomeBalance = &home
+			summary.NetWorth += home
+		}
+	}
+	for _, c := range currencies {
+		summary.NetWorthByCurrency = append(summary.NetWorthByCurrency, CurrencyAmount{Currency: c, Amount: byCurrency[c]})
+	}
+
+	// Amounts in the home currency are summed in SQL; the rest are summed
+	// per day so each day is converted at the rate nearest to it.
+	var flows []struct {
+		Currency string
+		Date     time.Time
+		Income   int64
+		Expense  int64
+	}
+	err = db.Raw(`
+		SELECT a.currency,
+			CASE WHEN a.currency = ? THEN ?::date ELSE t.date END AS date,
+			COALESCE(SUM(t.amount) FILTER (WHERE t.amount > 0), 0) AS income,
+			COALESCE(-SUM(t.amount) FILTER (WHERE t.amount < 0), 0) AS expense
+		FROM `+ledgerLines+` t
+		JOIN accounts a ON a.id = t.account_id
+		WHERE t.user_id = ? AND NOT t.is_transfer
+			AND t.date >= ? AND t.date < ?
+		GROUP BY 1, 2`, conv.home, from, userID, from, to).
+		Scan(&flows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flows {
+		if income, ok := conv.convert(f.Income, f.Currency, f.Date); ok {
+			summary.CashFlow.Income += income
+		}
+		if expense, ok := conv.convert(f.Expense, f.Currency, f.Date); ok {
+			summary.CashFlow.Expense += expense
+		}
+	}
+
+	var spending []struct {
+		CategoryID uint
+		Name       string
+		Currency   string
+		Date       time.Time
+		Spent      int64
+	}
+	err = db.Raw(`
+		SELECT c.id AS category_id, c.name, a.currency,
+			CASE WHEN a.currency = ? THEN ?::date ELSE t.date END AS date,
+			-SUM(t.amount) AS spent
+		FROM `+ledgerLines+` t
+		JOIN accounts a ON a.id = t.account_id
+		JOIN categories c ON c.id = t.category_id
+		WHERE t.user_id = ? AND NOT t.is_transfer
+			AND c.kind = ? AND t.date >= ? AND t.date < ?
+		GROUP BY 1, 2, 3, 4`, conv.home, from, userID, model.CategoryKindExpense, from, to).
+		Scan(&spending).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := map[uint]*CategorySpending{}
+	for _, row := range spending {
+		spent, ok := conv.convert(row.Spent, row.Currency, row.Date)
+		if !ok {
+			continue
+		}
+		c, ok := byCategory[row.CategoryID]
+		if !ok {
+			c = &CategorySpending{CategoryID: row.CategoryID, Name: row.Name}
+			byCategory[row.CategoryID] = c
+		}
+		c.Spent += spent
+	}
+	for _, c := range byCategory {
+		if c.Spent > 0 {
+			summary.TopCategories = append(summary.TopCategories, *c)
+		}
+	}
+	sort.Slice(summary.TopCategories, func(i, j int) bool {
+		a, b := summary.TopCategories[i], summary.TopCategories[j]
+		if a.Spent != b.Spent {
+			return a.Spent > b.Spent
+		}
+		return a.CategoryID < b.CategoryID
+	})
+	if len(summary.TopCategories) > topCategoriesLimit {
+		summary.TopCategories = summary.TopCategories[:topCategoriesLimit]
+	}
+
+	statuses, err := (&BudgetsStorage{s.db}).Statuses(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	summary.Budgets = BudgetHealth{Total: len(statuses), Items: statuses}
+	for _, st := range statuses {
+		if st.Overspent {
+			summary.Budgets.Overspent++
+		} else {
+			summary.Budgets.OnTrack++
+		}
+	}
+
+	err = db.Where("user_id = ?", userID).
+		Order("date DESC, id DESC").
+		Limit(recent).
+		Find(&summary.RecentTransactions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary.MissingRates = conv.missingRates()
+
+	return summary, nil
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/payoff"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidDebt = errors.New("debts can only be recorded for loan and credit card accounts")
+
+// PlanQuery describes a payoff simulation. Only debts on accounts in
+// Currency take part, since the extra payment is a single amount.
+type PlanQuery struct {
+	Currency string
+	Strategy payoff.Strategy
+	Extra    int64
+	Order    []uint
+}
+
+// DebtBalance is a debt as it enters the simulation. Balance is what is owed.
+type DebtBalance struct {
+	ID             uint   `json:"id"`
+	AccountID      uint   `json:"account_id"`
+	Name           string `json:"name"`
+	Balance        int64  `json:"balance"`
+	APR            int    `json:"apr"`
+	MinimumPayment int64  `json:"minimum_payment"`
+}
+
+type DebtPlan struct {
+	Currency string        `json:"currency"`
+	Debts    []DebtBalance `json:"debts"`
+	payoff.Result
+}
+
+type DebtsStorage struct {
+	db *gorm.DB
+}
+
+func (s *DebtsStorage) Create(ctx context.Context, d *model.Debt) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkDebtAccount(tx, d); err != nil {
+			return err
+		}
+
+		var count int64
+		err := tx.Model(&model.Debt{}).Where("account_id = ?", d.AccountID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrConflict
+		}
+
+		return tx.Create(d).Error
+	})
+}
+
+func (s *DebtsStorage) GetByID(ctx context.Context, userID, debtID uint) (*model.Debt, error) {
+	var d model.Debt
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", debtID, userID).
+		First(&d).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (s *DebtsStorage) List(ctx context.Context, userID uint) ([]model.Debt, error) {
+	debts := []model.Debt{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("apr DESC, id").
+		Find(&debts).Error
+
+	return debts, err
+}
+
+func (s *DebtsStorage) Update(ctx context.Context, d *model.Debt) error {
+	result := s.db.WithContext(ctx).
+		Model(d).
+		Where("user_id = ?", d.UserID).
+		Select("apr", "minimum_payment").
+		Updates(d)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *DebtsStorage) Delete(ctx context.Context, userID, debtID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", debtID, userID).
+		Delete(&model.Debt{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Plan simulates paying off the user's debts in q.Currency from their current
+// account balances. Accounts that are not in debt take no part, and are
+// dropped from a custom order.
+func (s *DebtsStorage) Plan(ctx context.Context, userID uint, q PlanQuery, today time.Time) (*DebtPlan, error) {
+	plan := &DebtPlan{Currency: q.Currency, Debts: []DebtBalance{}}
+
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT d.id, d.account_id, a.name, -a.balance AS balance, d.apr, d.minimum_payment
+		FROM debts d
+		JOIN accounts a ON a.id = d.account_id AND a.deleted_at IS NULL
+		WHERE d.user_id = ? AND d.deleted_at IS NULL AND a.currency = ? AND a.balance < 0
+		ORDER BY d.id`, userID, q.Currency).
+		Scan(&plan.Debts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	debts := make([]payoff.Debt, 0, len(plan.Debts))
+	owed := make(map[uint]bool, len(plan.Debts))
+	for _, d := range plan.Debts {
+		owed[d.ID] = true
+		debts = append(debts, payoff.Debt{
+			ID:             d.ID,
+			Balance:        d.Balance,
+			APR:            d.APR,
+			MinimumPayment: d.MinimumPayment,
+		})
+	}
+
+	order := make([]uint, 0, len(q.Order))
+	for _, id := range q.Order {
+		if owed[id] {
+			order = append(order, id)
+		}
+	}
+
+	plan.Result, err = payoff.Simulate(debts, q.Strategy, q.Extra, order, today)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func checkDebtAccount(tx *gorm.DB, d *model.Debt) error {
+	var account model.Account
+	err := tx.Where("id = ? AND user_id = ?", d.AccountID, d.UserID).First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if account.Type != model.AccountTypeLoan && account.Type != model.AccountTypeCreditCard {
+		return ErrInvalidDebt
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/dedupe"
+	"gorm.io/gorm"
+)
+
+const maxDuplicateCandidates = 1000
+
+var ErrInvalidMerge = errors.New("only transactions with the same account and amount can be merged")
+
+type DuplicatePair struct {
+	Score        float64             `json:"score"`
+	Transactions []model.Transaction `json:"transactions"`
+}
+
+type DuplicatesStorage struct {
+	db *gorm.DB
+}
+
+// Find returns suspected duplicate pairs scoring at least minScore, best
+// matches first. Candidates are narrowed down in SQL to pairs on the same
+// account with the same amount within windowDays of each other that the user
+// has not dismissed; the payee comparison is then done by dedupe.Score.
+func (s *DuplicatesStorage) Find(ctx context.Context, userID uint, windowDays int, minScore float64) ([]DuplicatePair, error) {
+	db := s.db.WithContext(ctx)
+
+	var candidates []struct {
+		AID uint
+		BID uint
+	}
+	err := db.Raw(`
+		SELECT a.id AS a_id, b.id AS b_id
+		FROM transactions a
+		JOIN transactions b ON b.account_id = a.account_id AND b.amount = a.amount
+			AND b.id > a.id AND abs(b.date - a.date) <= ?
+		WHERE a.user_id = ? AND b.user_id = a.user_id
+			AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND NOT a.is_transfer AND NOT b.is_transfer
+			AND NOT EXISTS (
+				SELECT 1 FROM duplicate_dismissals d
+				WHERE d.transaction_a_id = a.id AND d.transaction_b_id = b.id
+			)
+		ORDER BY a.date DESC
+		LIMIT ?`, windowDays, userID, maxDuplicateCandidates).
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := []DuplicatePair{}
+	if len(candidates) == 0 {
+		return pairs, nil
+	}
+
+	ids := make([]uint, 0, len(candidates)*2)
+	for _, c := range candidates {
+		ids = append(ids, c.AID, c.BID)
+	}
+
+	var txns []model.Transaction
+	if err := db.Where("id IN ?", ids).Find(&txns).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.Transaction, len(txns))
+	for _, t := range txns {
+		byID[t.ID] = t
+	}
+
+	for _, c := range candidates {
+		a, b := byID[c.AID], byID[c.BID]
+		if score := dedupe.Score(a, b, windowDays); score >= minScore {
+			pairs = append(pairs, DuplicatePair{Score: score, Transactions: []model.Transaction{a, b}})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Score > pairs[j].Score
+	})
+
+	return pairs, nil
+}
+
+// Dismiss remembers that the two transactions are not duplicates.
+func (s *DuplicatesStorage) Dismiss(ctx context.Context, userID, aID, bID uint) error {
+	if aID == bID {
+		return ErrNotFound
+	}
+	if aID > bID {
+		aID, bID = bID, aID
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.Transaction{}).
+			Where("id IN ? AND user_id = ?", []uint{aID, bID}, userID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count != 2 {
+			return ErrNotFound
+		}
+
+		return tx.Clauses(onConflictDoNothing).Create(&model.DuplicateDismissal{
+			UserID:         userID,
+			TransactionAID: aID,
+			TransactionBID: bID,
+		}).Error
+	})
+}
+
+// Merge folds the transaction removeID into keepID: any payee, memo, category
+// or FITID missing on the kept transaction is taken from the removed one, its
+// attachments move over, and the removed transaction is deleted with its
+// splits and its effect on the balance reversed. Both have to be on the same
+// account with the same amount, as the duplicate matcher requires.
+func (s *DuplicatesStorage) Merge(ctx context.Context, userID, keepID, removeID uint) (*model.Transaction, error) {
+	if keepID == removeID {
+		return nil, ErrNotFound
+	}
+
+	var keep model.Transaction
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var txns []model.Transaction
+		err := tx.Clauses(lockForUpdate).
+			Where("id IN ? AND user_id = ?", []uint{keepID, removeID}, userID).
+			Find(&txns).Error
+		if err != nil {
+			return err
+		}
+		if len(txns) != 2 {
+			return ErrNotFound
+		}
+
+		remove := txns[0]
+		keep = txns[1]
+		if keep.ID != keepID {
+			keep, remove = remove, keep
+		}
+		if keep.IsTransfer || remove.IsTransfer {
+			return ErrInvalidTransfer
+		}
+		if keep.AccountID != remove.AccountID || keep.Amount != remove.Amount {
+			return ErrInvalidMerge
+		}
+
+		if err := adjustBalance(tx, userID, remove.AccountID, -remove.Amount); err != nil {
+			return err
+		}
+		if remove.IsSplit {
+			if err := tx.Where("transaction_id = ?", remove.ID).Delete(&model.TransactionSplit{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&remove).Error; err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.Attachment{}).
+			Where("transaction_id = ?", remove.ID).
+			Update("transaction_id", keep.ID).Error
+		if err != nil {
+			return err
+		}
+
+		if keep.Payee == "" {
+			keep.Payee = remove.Payee
+		}
+		if keep.Memo == "" {
+			keep.Memo = remove.Memo
+		}
+		if keep.CategoryID == nil && !keep.IsSplit {
+			keep.CategoryID = remove.CategoryID
+		}
+		if keep.FITID == "" {
+			keep.FITID = remove.FITID
+		}
+
+		return tx.Model(&keep).
+			Select("payee", "memo", "category_id", "fit_id").
+			Updates(&keep).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &keep, nil
+}
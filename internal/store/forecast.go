@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/forecast"
+	"gorm.io/gorm"
+)
+
+// forecastHistoryDays is how far back transactions are scanned for repeating
+// payees, and forecastBudgetDays how far back to look for the account a
+// category is usually paid from.
+const (
+	forecastHistoryDays = 180
+	forecastBudgetDays  = 90
+)
+
+type ForecastStorage struct {
+	db *gorm.DB
+}
+
+// Project gathers the user's balances, recurring items, recent history and
+// budgets and hands them to forecast.Project for the days starting today.
+// Budgets are in the home currency and are converted into the currency of
+// the account they are expected to be spent from.
+func (s *ForecastStorage) Project(ctx context.Context, userID uint, today time.Time, days int) (*forecast.Forecast, error) {
+	db := s.db.WithContext(ctx)
+	end := today.AddDate(0, 0, days-1)
+
+	in := forecast.Input{Start: today, Days: days}
+
+	var accounts []model.Account
+	if err := db.Where("user_id = ?", userID).Order("name, id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	currencies := map[uint]string{}
+	for _, a := range accounts {
+		in.Accounts = append(in.Accounts, forecast.Account{
+			ID:        a.ID,
+			Balance:   a.Balance,
+			Threshold: a.LowBalanceThreshold,
+		})
+		currencies[a.ID] = a.Currency
+	}
+
+	var recurring []model.RecurringTransaction
+	if err := db.Where("user_id = ? AND next_date IS NOT NULL", userID).Find(&recurring).Error; err != nil {
+		return nil, err
+	}
+	for i := range recurring {
+		rt := &recurring[i]
+		for _, d := range RecurrenceRule(rt).Between(*rt.NextDate, end) {
+			if slices.Contains(rt.SkippedDates, d.Format(dateLayout)) {
+				continue
+			}
+			// Anything already due will be posted by the scheduler shortly.
+			if d.Before(today) {
+				d = today
+			}
+			in.Events = append(in.Events, forecast.Event{
+				AccountID:  rt.AccountID,
+				CategoryID: rt.CategoryID,
+				Date:       d,
+				Amount:     rt.Amount,
+				Payee:      rt.Payee,
+				Source:     forecast.SourceRecurring,
+			})
+		}
+	}
+
+	err := db.Model(&model.Transaction{}).
+		Select("account_id, category_id, date, amount, payee").
+		Where("user_id = ? AND NOT is_transfer AND recurring_id IS NULL AND payee <> ''", userID).
+		Where("date >= ? AND date < ?", today.AddDate(0, 0, -forecastHistoryDays), today).
+		Order("date").
+		Scan(&in.History).Error
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := s.budgets(ctx, userID, today, currencies)
+	if err != nil {
+		return nil, err
+	}
+	in.Budgets = budgets
+
+	f := forecast.Project(in)
+	return &f, nil
+}
+
+// budgets turns the user's budgets for the current month into forecast
+// budgets, each charged to the account most of the category's spending came
+// from recently, or else to the account with the most spending overall. A
+// budget under a budgeted parent category is left out, as the parent's
+// allowance already covers its spending.
+func (s *ForecastStorage) budgets(ctx context.Context, userID uint, today time.Time, currencies map[uint]string) ([]forecast.Budget, error) {
+	db := s.db.WithContext(ctx)
+
+	statuses, err := (&BudgetsStorage{s.db}).Statuses(ctx, userID, today)
+	if err != nil || len(statuses) == 0 {
+		return nil, err
+	}
+
+	since := today.AddDate(0, 0, -forecastBudgetDays)
+
+	var usual []struct {
+		CategoryID uint
+		AccountID  uint
+	}
+	err = db.Raw(`
+		SELECT DISTINCT ON (category_id) category_id, account_id
+		FROM `+ledgerLines+` t
+		WHERE user_id = ? AND category_id IS NOT NULL AND NOT is_transfer
+			AND amount < 0 AND date >= ?
+		GROUP BY category_id, account_id
+		ORDER BY category_id, SUM(amount), account_id`, userID, since).
+		Scan(&usual).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := map[uint]uint{}
+	for _, u := range usual {
+		byCategory[u.CategoryID] = u.AccountID
+	}
+
+	var fallback struct {
+		AccountID uint
+	}
+	err = db.Raw(`
+		SELECT account_id
+		FROM transactions
+		WHERE user_id = ? AND NOT is_transfer AND deleted_at IS NULL AND amount < 0 AND date >= ?
+		GROUP BY account_id
+		ORDER BY SUM(amount), account_id
+		LIMIT 1`, userID, since).
+		Scan(&fallback).Error
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := loadConverter(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []model.Category
+	if err := db.Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]*uint, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+	budgeted := make(map[uint]bool, len(statuses))
+	for _, st := range statuses {
+		budgeted[st.CategoryID] = true
+	}
+
+	var budgets []forecast.Budget
+	for _, st := range statuses {
+		if hasBudgetedAncestor(st.CategoryID, parents, budgeted) {
+			continue
+		}
+
+		accountID, ok := byCategory[st.CategoryID]
+		if !ok {
+			accountID = fallback.AccountID
+		}
+		currency, ok := currencies[accountID]
+		if !ok {
+			continue
+		}
+
+		monthly, ok := conv.fromHome(st.Budgeted, currency, today)
+		if !ok {
+			continue
+		}
+		remaining, _ := conv.fromHome(st.Remaining, currency, today)
+
+		budgets = append(budgets, forecast.Budget{
+			CategoryID: st.CategoryID,
+			AccountID:  accountID,
+			Monthly:    monthly,
+			Remaining:  remaining,
+		})
+	}
+
+	return budgets, nil
+}
+
+// hasBudgetedAncestor reports whether any category above categoryID has a
+// budget.
+func hasBudgetedAncestor(categoryID uint, parents map[uint]*uint, budgeted map[uint]bool) bool {
+	seen := map[uint]bool{categoryID: true}
+	for parent := parents[categoryID]; parent != nil && !seen[*parent]; parent = parents[*parent] {
+		if budgeted[*parent] {
+			return true
+		}
+		seen[*parent] = true
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/budget"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/goal"
+	"gorm.io/gorm"
+)
+
+// goalsLockKey is the Postgres advisory lock held while re-evaluating every
+// goal, so a status change is only reported by one API replica.
+const goalsLockKey int64 = 0x66696e74676f6c31
+
+// goalContributionMonths is how many months, the current one included, the
+// average monthly contribution is taken over.
+const goalContributionMonths = 6
+
+var ErrInvalidGoal = errors.New("invalid goal")
+
+// GoalProgress is a goal together with its evaluated progress, in the
+// currency of its account.
+type GoalProgress struct {
+	model.Goal
+	Currency string        `json:"currency"`
+	Progress goal.Progress `json:"progress"`
+}
+
+// GoalTransition is a goal whose status changed since it was last evaluated.
+type GoalTransition struct {
+	GoalProgress
+	Previous goal.Status `json:"previous"`
+}
+
+type GoalsStorage struct {
+	db *gorm.DB
+}
+
+func (s *GoalsStorage) Create(ctx context.Context, g *model.Goal) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkGoalAccount(tx, g); err != nil {
+			return err
+		}
+
+		return tx.Create(g).Error
+	})
+}
+
+func (s *GoalsStorage) GetByID(ctx context.Context, userID, goalID uint) (*model.Goal, error) {
+	var g model.Goal
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", goalID, userID).
+		First(&g).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+func (s *GoalsStorage) List(ctx context.Context, userID uint) ([]model.Goal, error) {
+	goals := []model.Goal{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("target_date NULLS LAST, id").
+		Find(&goals).Error
+
+	return goals, err
+}
+
+func (s *GoalsStorage) Update(ctx context.Context, g *model.Goal) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkGoalAccount(tx, g); err != nil {
+			return err
+		}
+
+		result := tx.Model(g).
+			Where("user_id = ?", g.UserID).
+			Select("account_id", "name", "target_amount", "target_date", "earmarked").
+			Updates(g)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// Delete removes a goal along with its contributions.
+func (s *GoalsStorage) Delete(ctx context.Context, userID, goalID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", goalID, userID).Delete(&model.Goal{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return tx.Where("goal_id = ?", goalID).Delete(&model.GoalContribution{}).Error
+	})
+}
+
+// Contribute records money put aside for, or taken back from, an earmarked
+// goal. Goals tracking a whole account balance do not take contributions.
+func (s *GoalsStorage) Contribute(ctx context.Context, c *model.GoalContribution) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var g model.Goal
+		err := tx.Where("id = ? AND user_id = ?", c.GoalID, c.UserID).First(&g).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if !g.Earmarked {
+			return ErrInvalidGoal
+		}
+
+		return tx.Create(c).Error
+	})
+}
+
+func (s *GoalsStorage) Contributions(ctx context.Context, userID, goalID uint) ([]model.GoalContribution, error) {
+	contributions := []model.GoalContribution{}
+	err := s.db.WithContext(ctx).
+		Where("goal_id = ? AND user_id = ?", goalID, userID).
+		Order("date DESC, id DESC").
+		Find(&contributions).Error
+
+	return contributions, err
+}
+
+// Progress evaluates the given goals as of today.
+func (s *GoalsStorage) Progress(ctx context.Context, goals []model.Goal, today time.Time) ([]GoalProgress, error) {
+	return evaluateGoals(s.db.WithContext(ctx), goals, today)
+}
+
+// CheckAll re-evaluates every goal, stores the new statuses and returns the
+// goals whose status changed. It is a no-op while another process holds the
+// lock.
+func (s *GoalsStorage) CheckAll(ctx context.Context, today time.Time) ([]GoalTransition, error) {
+	var transitions []GoalTransition
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", goalsLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var goals []model.Goal
+		return tx.Order("id").FindInBatches(&goals, 500, func(*gorm.DB, int) error {
+			evaluated, err := evaluateGoals(tx, goals, today)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range evaluated {
+				if p.Status == string(p.Progress.Status) {
+					continue
+				}
+
+				previous := goal.Status(p.Status)
+				p.Status = string(p.Progress.Status)
+				if err := tx.Model(&p.Goal).UpdateColumn("status", p.Status).Error; err != nil {
+					return err
+				}
+
+				transitions = append(transitions, GoalTransition{GoalProgress: p, Previous: previous})
+			}
+
+			return nil
+		}).Error
+	})
+
+	return transitions, err
+}
+
+// evaluateGoals loads what each goal needs with a fixed number of queries and
+// runs goal.Evaluate on it. Goals on an account count its whole balance and
+// its net monthly flows; earmarked goals count their contributions.
+func evaluateGoals(db *gorm.DB, goals []model.Goal, today time.Time) ([]GoalProgress, error) {
+	evaluated := make([]GoalProgress, 0, len(goals))
+	if len(goals) == 0 {
+		return evaluated, nil
+	}
+
+	thisMonth := budget.MonthStart(today)
+	windowStart := thisMonth.AddDate(0, -(goalContributionMonths - 1), 0)
+
+	accountIDs := make([]uint, 0, len(goals))
+	goalIDs := make([]uint, 0, len(goals))
+	for _, g := range goals {
+		accountIDs = append(accountIDs, g.AccountID)
+		goalIDs = append(goalIDs, g.ID)
+	}
+
+	var accounts []model.Account
+	if err := db.Unscoped().Select("id, currency, balance").Where("id IN ?", accountIDs).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	byAccount := make(map[uint]model.Account, len(accounts))
+	for _, a := range accounts {
+		byAccount[a.ID] = a
+	}
+
+	var flows []struct {
+		ID     uint
+		Month  time.Time
+		Amount int64
+	}
+	err := db.Raw(`
+		SELECT account_id AS id, date_trunc('month', date)::date AS month, SUM(amount) AS amount
+		FROM transactions
+		WHERE account_id IN ? AND deleted_at IS NULL AND date >= ? AND date <= ?
+		GROUP BY 1, 2`, accountIDs, windowStart, today).
+		Scan(&flows).Error
+	if err != nil {
+		return nil, err
+	}
+	accountFlows := map[uint]map[time.Time]int64{}
+	for _, f := range flows {
+		if accountFlows[f.ID] == nil {
+			accountFlows[f.ID] = map[time.Time]int64{}
+		}
+		accountFlows[f.ID][budget.MonthStart(f.Month)] = f.Amount
+	}
+
+	var contributions []struct {
+		ID     uint
+		Month  time.Time
+		Amount int64
+	}
+	err = db.Raw(`
+		SELECT goal_id AS id, date_trunc('month', date)::date AS month, SUM(amount) AS amount
+		FROM goal_contributions
+		WHERE goal_id IN ? AND date <= ?
+		GROUP BY 1, 2`, goalIDs, today).
+		Scan(&contributions).Error
+	if err != nil {
+		return nil, err
+	}
+	goalFlows := map[uint]map[time.Time]int64{}
+	goalTotals := map[uint]int64{}
+	for _, c := range contributions {
+		if goalFlows[c.ID] == nil {
+			goalFlows[c.ID] = map[time.Time]int64{}
+		}
+		goalFlows[c.ID][budget.MonthStart(c.Month)] = c.Amount
+		goalTotals[c.ID] += c.Amount
+	}
+
+	for _, g := range goals {
+		account := byAccount[g.AccountID]
+		in := goal.Input{
+			Target:     g.TargetAmount,
+			TargetDate: g.TargetDate,
+			Current:    account.Balance,
+		}
+
+		monthly := accountFlows[g.AccountID]
+		from := windowStart
+		if g.Earmarked {
+			in.Current = goalTotals[g.ID]
+			monthly = goalFlows[g.ID]
+			if created := budget.MonthStart(g.CreatedAt); created.After(from) {
+				from = created
+			}
+		}
+		for m := from; !m.After(thisMonth); m = m.AddDate(0, 1, 0) {
+			in.Contributions = append(in.Contributions, monthly[m])
+		}
+
+		evaluated = append(evaluated, GoalProgress{
+			Goal:     g,
+			Currency: account.Currency,
+			Progress: goal.Evaluate(in, today),
+		})
+	}
+
+	return evaluated, nil
+}
+
+func checkGoalAccount(tx *gorm.DB, g *model.Goal) error {
+	var count int64
+	err := tx.Model(&model.Account{}).
+		Where("id = ? AND user_id = ?", g.AccountID, g.UserID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
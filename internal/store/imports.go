@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+const importInsertBatchSize = 500
+
+type ImportsStorage struct {
+	db *gorm.DB
+}
+
+// Commit records batch and inserts all of its transactions in one database
+// transaction, moving the account balance by their total. Either every row is
+// imported or none is. Transactions whose FITID is already on the account are
+// skipped and counted in batch.DuplicateCount, and the user's rules are run
+// over the rest before they are inserted.
+func (s *ImportsStorage) Commit(ctx context.Context, batch *model.ImportBatch, txns []model.Transaction) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var account model.Account
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", batch.AccountID, batch.UserID).
+			First(&account).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		fitids := make([]string, 0, len(txns))
+		for _, t := range txns {
+			if t.FITID != "" {
+				fitids = append(fitids, t.FITID)
+			}
+		}
+
+		existing, err := existingFITIDs(tx, batch.AccountID, fitids)
+		if err != nil {
+			return err
+		}
+
+		fresh := txns[:0]
+		for _, t := range txns {
+			if t.FITID != "" {
+				if existing[t.FITID] {
+					batch.DuplicateCount++
+					continue
+				}
+				existing[t.FITID] = true
+			}
+			fresh = append(fresh, t)
+		}
+		txns = fresh
+
+		batch.RowCount = len(txns)
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		if len(txns) == 0 {
+			return nil
+		}
+
+		engine, err := loadRuleEngine(tx, batch.UserID)
+		if err != nil {
+			return err
+		}
+
+		var total int64
+		tagsByRow := make([][]string, len(txns))
+		for i := range txns {
+			txns[i].UserID = batch.UserID
+			txns[i].AccountID = batch.AccountID
+			txns[i].ImportBatchID = &batch.ID
+			tagsByRow[i] = engine.Apply(&txns[i], false).AddTags
+			total += txns[i].Amount
+		}
+
+		if err := tx.CreateInBatches(txns, importInsertBatchSize).Error; err != nil {
+			return err
+		}
+
+		tags := map[uint][]string{}
+		for i, names := range tagsByRow {
+			if len(names) > 0 {
+				tags[txns[i].ID] = names
+			}
+		}
+		if err := attachTags(tx, batch.UserID, tags); err != nil {
+			return err
+		}
+
+		return adjustBalance(tx, batch.UserID, batch.AccountID, total)
+	})
+}
+
+// ExistingFITIDs reports which of fitids are already used by live
+// transactions on the account.
+func (s *ImportsStorage) ExistingFITIDs(ctx context.Context, userID, accountID uint, fitids []string) (map[string]bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&model.Account{}).
+		Where("id = ? AND user_id = ?", accountID, userID).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNotFound
+	}
+
+	return existingFITIDs(s.db.WithContext(ctx), accountID, fitids)
+}
+
+func existingFITIDs(db *gorm.DB, accountID uint, fitids []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(fitids))
+
+	for start := 0; start < len(fitids); start += importInsertBatchSize {
+		end := min(start+importInsertBatchSize, len(fitids))
+
+		var found []string
+		err := db.Model(&model.Transaction{}).
+			Where("account_id = ? AND fit_id IN ?", accountID, fitids[start:end]).
+			Pluck("fit_id", &found).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range found {
+			existing[f] = true
+		}
+	}
+
+	return existing, nil
+}
+
+func (s *ImportsStorage) GetByID(ctx context.Context, userID, batchID uint) (*model.ImportBatch, error) {
+	var batch model.ImportBatch
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", batchID, userID).
+		First(&batch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+func (s *ImportsStorage) List(ctx context.Context, userID uint) ([]model.ImportBatch, error) {
+	batches := []model.ImportBatch{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&batches).Error
+
+	return batches, err
+}
+
+// Undo deletes every transaction that is still part of the batch along with
+// their splits and attachments, reverses their effect on the account balance
+// and then removes the batch itself. It returns the blob keys of the deleted
+// attachments so the caller can remove the files.
+func (s *ImportsStorage) Undo(ctx context.Context, userID, batchID uint) ([]string, error) {
+	var keys []string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var batch model.ImportBatch
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", batchID, userID).
+			First(&batch).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		var txns []model.Transaction
+		err = tx.Where("user_id = ? AND import_batch_id = ?", userID, batchID).Find(&txns).Error
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint, len(txns))
+		totals := map[uint]int64{}
+		for i, t := range txns {
+			ids[i] = t.ID
+			totals[t.AccountID] += t.Amount
+		}
+		for accountID, total := range totals {
+			if err := adjustBalance(tx, userID, accountID, -total); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) > 0 {
+			if err := tx.Where("transaction_id IN ?", ids).Delete(&model.TransactionSplit{}).Error; err != nil {
+				return err
+			}
+			if keys, err = deleteAttachments(tx, userID, ids); err != nil {
+				return err
+			}
+
+			err = tx.Where("id IN ? AND user_id = ?", ids, userID).Delete(&model.Transaction{}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&batch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,17 @@
+package store
+
+// ledgerLines is a subquery with one row per categorised line of a live
+// transaction: every line of a split transaction, and every other
+// transaction as a whole. Reports and budgets aggregate over it instead of
+// the transactions table so split amounts count towards their own
+// categories. The id column is the transaction's id.
+const ledgerLines = `(
+	SELECT t.id, t.user_id, t.account_id, s.category_id, s.amount, t.date, t.payee, t.is_transfer
+	FROM transactions t
+	JOIN transaction_splits s ON s.transaction_id = t.id
+	WHERE t.is_split AND t.deleted_at IS NULL
+	UNION ALL
+	SELECT t.id, t.user_id, t.account_id, t.category_id, t.amount, t.date, t.payee, t.is_transfer
+	FROM transactions t
+	WHERE NOT t.is_split AND t.deleted_at IS NULL
+)`
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+// ErrCodeUsed is returned for a TOTP code whose time step was already used.
+var ErrCodeUsed = errors.New("code already used")
+
+type MFAStorage struct {
+	db *gorm.DB
+}
+
+// StartTOTP stores the secret of a new enrolment, replacing one that was
+// never confirmed. It fails with ErrConflict once TOTP is enabled.
+func (s *MFAStorage) StartTOTP(ctx context.Context, userID uint, secret string) error {
+	result := s.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_enabled = false", userID).
+		Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// EnableTOTP finishes an enrolment confirmed with the code of the given
+// time step and replaces the user's recovery codes.
+func (s *MFAStorage) EnableTOTP(ctx context.Context, userID uint, step int64, codeHashes []string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).
+			Where("id = ? AND totp_enabled = false AND totp_secret <> ''", userID).
+			Updates(map[string]any{"totp_enabled": true, "totp_last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+
+		return replaceRecoveryCodes(tx, userID, codeHashes, now)
+	})
+}
+
+// DisableTOTP turns two-factor authentication off and forgets the secret and
+// recovery codes.
+func (s *MFAStorage) DisableTOTP(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep records that a code of the given time step was accepted. It
+// fails with ErrCodeUsed when that step, or a later one, already was.
+func (s *MFAStorage) UseTOTPStep(ctx context.Context, userID uint, step int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCodeUsed
+	}
+
+	return nil
+}
+
+// UseRecoveryCode uses up the unused recovery code with the given hash. It
+// fails with ErrNotFound when the user has no such code.
+func (s *MFAStorage) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) error {
+	result := s.db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores new
+// ones.
+func (s *MFAStorage) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes, now)
+	})
+}
+
+// RecoveryCodesLeft counts the user's unused recovery codes.
+func (s *MFAStorage) RecoveryCodesLeft(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (s *MFAStorage) CreateChallenge(ctx context.Context, c *model.MFAChallenge) error {
+	return s.db.WithContext(ctx).Create(c).Error
+}
+
+// AttemptChallenge counts an attempt against the challenge with the given
+// hash and returns it. Expired challenges and those already tried
+// maxAttempts times fail with ErrNotFound.
+func (s *MFAStorage) AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int, now time.Time) (*model.MFAChallenge, error) {
+	var c model.MFAChallenge
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(lockForUpdate).
+			Where("token_hash = ? AND expires_at > ? AND attempts < ?", tokenHash, now, maxAttempts).
+			First(&c).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		c.Attempts++
+		return tx.Model(&c).UpdateColumn("attempts", c.Attempts).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// DeleteChallenge removes a challenge once it has been passed, along with
+// any expired ones.
+func (s *MFAStorage) DeleteChallenge(ctx context.Context, challengeID uint, now time.Time) error {
+	return s.db.WithContext(ctx).
+		Where("id = ? OR expires_at <= ?", challengeID, now).
+		Delete(&model.MFAChallenge{}).Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string, now time.Time) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]model.RecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, model.RecoveryCode{UserID: userID, CodeHash: h, CreatedAt: now})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	return tx.Create(&codes).Error
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// netWorthLockKey is the Postgres advisory lock held while taking the daily
+// net worth snapshots, so only one API replica does it at a time.
+const netWorthLockKey int64 = 0x66696e746e777331
+
+// NetWorthPoint is the net worth at the end of one period, i.e. the sum of
+// every account's last snapshot within it. Assets and Liabilities split it
+// into positive and negative balances.
+type NetWorthPoint struct {
+	Period      time.Time `json:"period"`
+	NetWorth    int64     `json:"net_worth"`
+	Assets      int64     `json:"assets"`
+	Liabilities int64     `json:"liabilities"`
+}
+
+type NetWorthSeries struct {
+	HomeCurrency string          `json:"home_currency"`
+	Interval     string          `json:"interval"`
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	Points       []NetWorthPoint `json:"points"`
+	MissingRates []string        `json:"missing_rates"`
+}
+
+type NetWorthStorage struct {
+	db *gorm.DB
+}
+
+// SnapshotDue records the closing balance on date of every account that has
+// no snapshot for it yet and returns how many snapshots were written. Users
+// who have no snapshots at all get their whole history backfilled from the
+// ledger; others are caught up from their latest snapshot. Like PostDue it is
+// a no-op while another process holds the lock.
+func (s *NetWorthStorage) SnapshotDue(ctx context.Context, date time.Time) (int, error) {
+	written := 0
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", netWorthLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var users []struct {
+			UserID uint
+			Last   *time.Time
+		}
+		err := tx.Raw(`
+			SELECT a.user_id, (SELECT MAX(s.date) FROM net_worth_snapshots s WHERE s.user_id = a.user_id) AS last
+			FROM accounts a
+			WHERE a.deleted_at IS NULL AND NOT EXISTS (
+				SELECT 1 FROM net_worth_snapshots s WHERE s.account_id = a.id AND s.date = ?
+			)
+			GROUP BY a.user_id
+			ORDER BY a.user_id`, date).
+			Scan(&users).Error
+		if err != nil {
+			return err
+		}
+
+		for _, u := range users {
+			var from *time.Time
+			if u.Last != nil {
+				next := u.Last.AddDate(0, 0, 1)
+				if next.After(date) {
+					next = date
+				}
+				from = &next
+			}
+
+			n, err := writeSnapshots(tx, u.UserID, from, date, false)
+			if err != nil {
+				return err
+			}
+			written += n
+		}
+
+		return nil
+	})
+
+	return written, err
+}
+
+// Backfill rebuilds a user's snapshots from the start of their ledger up to
+// and including through, replacing existing ones. It is meant for after old
+// statements have been imported, which changes every balance since.
+func (s *NetWorthStorage) Backfill(ctx context.Context, userID uint, through time.Time) (int, error) {
+	written := 0
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		n, err := writeSnapshots(tx, userID, nil, through, true)
+		written = n
+		return err
+	})
+
+	return written, err
+}
+
+// Series returns the user's net worth in their home currency for each
+// interval between from and to. Snapshots taken in another home currency, or
+// without a rate at the time, are converted again from the account balance.
+func (s *NetWorthStorage) Series(ctx context.Context, userID uint, from, to time.Time, interval string) (*NetWorthSeries, error) {
+	db := s.db.WithContext(ctx)
+
+	conv, err := loadConverter(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Period time.Time
+		model.NetWorthSnapshot
+	}
+	err = db.Raw(`
+		SELECT DISTINCT ON (account_id, period) *
+		FROM (
+			SELECT date_trunc(?, date::timestamp)::date AS period, *
+			FROM net_worth_snapshots
+			WHERE user_id = ? AND date >= ? AND date <= ?
+		) s
+		ORDER BY account_id, period, date DESC`, interval, userID, from, to).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := map[time.Time]*NetWorthPoint{}
+	for _, row := range rows {
+		balance := row.HomeBalance
+		if balance == nil || row.HomeCurrency != conv.home {
+			converted, ok := conv.convert(row.Balance, row.Currency, row.Date)
+			if !ok {
+				continue
+			}
+			balance = &converted
+		}
+
+		p, ok := points[row.Period]
+		if !ok {
+			p = &NetWorthPoint{Period: row.Period}
+			points[row.Period] = p
+		}
+		p.NetWorth += *balance
+		if *balance >= 0 {
+			p.Assets += *balance
+		} else {
+			p.Liabilities -= *balance
+		}
+	}
+
+	series := &NetWorthSeries{
+		HomeCurrency: conv.home,
+		Interval:     interval,
+		From:         from,
+		To:           to,
+		Points:       make([]NetWorthPoint, 0, len(points)),
+		MissingRates: conv.missingRates(),
+	}
+	for _, p := range points {
+		series.Points = append(series.Points, *p)
+	}
+	sort.Slice(series.Points, func(i, j int) bool {
+		return series.Points[i].Period.Before(series.Points[j].Period)
+	})
+
+	return series, nil
+}
+
+// writeSnapshots derives the closing balance of each of the user's accounts
+// for every day in [from, to] from the ledger and stores it. When from is nil
+// it starts at the user's earliest transaction or account creation, and no
+// account gets snapshots from before its own first transaction or creation.
+// Existing snapshots are replaced only when overwrite is set.
+func writeSnapshots(tx *gorm.DB, userID uint, from *time.Time, to time.Time, overwrite bool) (int, error) {
+	if from == nil {
+		var start struct {
+			Date *time.Time
+		}
+		err := tx.Raw(`
+			SELECT MIN(d) AS date FROM (
+				SELECT MIN(date) AS d FROM transactions WHERE user_id = ? AND deleted_at IS NULL
+				UNION ALL
+				SELECT MIN(created_at)::date FROM accounts WHERE user_id = ? AND deleted_at IS NULL
+			) s`, userID, userID).
+			Scan(&start).Error
+		if err != nil {
+			return 0, err
+		}
+		if start.Date == nil {
+			return 0, nil
+		}
+		from = start.Date
+	}
+	if from.After(to) {
+		return 0, nil
+	}
+
+	conv, err := loadConverter(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var balances []struct {
+		AccountID uint
+		Currency  string
+		Date      time.Time
+		Balance   int64
+	}
+	err = tx.Raw(`
+		WITH days AS (
+			SELECT generate_series(?::date, ?::date, interval '1 day')::date AS date
+		), earlier AS (
+			SELECT account_id, SUM(amount) AS amount
+			FROM transactions
+			WHERE user_id = ? AND deleted_at IS NULL AND date < ?
+			GROUP BY account_id
+		), daily AS (
+			SELECT account_id, date, SUM(amount) AS amount
+			FROM transactions
+			WHERE user_id = ? AND deleted_at IS NULL AND date >= ? AND date <= ?
+			GROUP BY account_id, date
+		), first AS (
+			SELECT account_id, MIN(date) AS date
+			FROM transactions
+			WHERE user_id = ? AND deleted_at IS NULL
+			GROUP BY account_id
+		)
+		SELECT a.id AS account_id, a.currency, d.date,
+			a.opening_balance + COALESCE(e.amount, 0)
+				+ SUM(COALESCE(dl.amount, 0)) OVER (PARTITION BY a.id ORDER BY d.date) AS balance
+		FROM accounts a
+		CROSS JOIN days d
+		LEFT JOIN earlier e ON e.account_id = a.id
+		LEFT JOIN daily dl ON dl.account_id = a.id AND dl.date = d.date
+		LEFT JOIN first f ON f.account_id = a.id
+		WHERE a.user_id = ? AND a.deleted_at IS NULL
+			AND d.date >= LEAST(a.created_at::date, COALESCE(f.date, a.created_at::date))
+		ORDER BY a.id, d.date`,
+		*from, to, userID, *from, userID, *from, to, userID, userID).
+		Scan(&balances).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(balances) == 0 {
+		return 0, nil
+	}
+
+	snapshots := make([]model.NetWorthSnapshot, 0, len(balances))
+	for _, b := range balances {
+		snapshot := model.NetWorthSnapshot{
+			UserID:       userID,
+			AccountID:    b.AccountID,
+			Date:         b.Date,
+			Currency:     b.Currency,
+			Balance:      b.Balance,
+			HomeCurrency: conv.home,
+		}
+		if home, ok := conv.convert(b.Balance, b.Currency, b.Date); ok {
+			snapshot.HomeBalance = &home
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	conflict := onConflictDoNothing
+	if overwrite {
+		conflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"currency", "balance", "home_currency", "home_balance"}),
+		}
+	}
+
+	result := tx.Clauses(conflict).CreateInBatches(snapshots, 500)
+	return int(result.RowsAffected), result.Error
+}
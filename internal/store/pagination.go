@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TransactionQuery holds the filters and cursor used to page through a user's
+// transactions, newest first.
+type TransactionQuery struct {
+	Limit      int        `json:"limit" validate:"gte=1,lte=200"`
+	Cursor     string     `json:"cursor"`
+	AccountID  *uint      `json:"account_id"`
+	CategoryID *uint      `json:"category_id"`
+	TagID      *uint      `json:"tag_id"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	MinAmount  *int64     `json:"min_amount"`
+	MaxAmount  *int64     `json:"max_amount"`
+	Search     string     `json:"search" validate:"max=100"`
+}
+
+// transactionCursor is the position of the last row of a page. Rows are
+// ordered by (date, id) descending so the pair is unique and stable even when
+// new transactions are inserted between requests.
+type transactionCursor struct {
+	Date time.Time
+	ID   uint
+}
+
+func (c transactionCursor) encode() string {
+	raw := c.Date.Format(dateLayout) + "|" + strconv.FormatUint(uint64(c.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTransactionCursor(s string) (transactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return transactionCursor{}, ErrInvalidCursor
+	}
+
+	date, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return transactionCursor{}, ErrInvalidCursor
+	}
+
+	d, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return transactionCursor{}, ErrInvalidCursor
+	}
+
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return transactionCursor{}, ErrInvalidCursor
+	}
+
+	return transactionCursor{Date: d, ID: uint(n)}, nil
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateQuery narrows the exchange rates returned by List. Empty fields match
+// everything.
+type RateQuery struct {
+	Base  string
+	Quote string
+	From  *time.Time
+	To    *time.Time
+}
+
+type ExchangeRatesStorage struct {
+	db *gorm.DB
+}
+
+// Upsert stores the rates, replacing any existing rate for the same pair and
+// date, and returns what was stored. When the batch holds the same pair and
+// date more than once the last one wins, since a single statement cannot
+// update a row twice.
+func (s *ExchangeRatesStorage) Upsert(ctx context.Context, userID uint, rates []model.ExchangeRate) ([]model.ExchangeRate, error) {
+	type pairDate struct {
+		base, quote, date string
+	}
+
+	index := make(map[pairDate]int, len(rates))
+	unique := make([]model.ExchangeRate, 0, len(rates))
+	for _, r := range rates {
+		r.UserID = userID
+		r.Base = strings.ToUpper(r.Base)
+		r.Quote = strings.ToUpper(r.Quote)
+
+		k := pairDate{r.Base, r.Quote, r.Date.Format(time.DateOnly)}
+		if i, ok := index[k]; ok {
+			unique[i] = r
+			continue
+		}
+		index[k] = len(unique)
+		unique = append(unique, r)
+	}
+
+	err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "base"}, {Name: "quote"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+		}).
+		CreateInBatches(unique, 500).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return unique, nil
+}
+
+func (s *ExchangeRatesStorage) List(ctx context.Context, userID uint, q RateQuery) ([]model.ExchangeRate, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if q.Base != "" {
+		query = query.Where("base = ?", strings.ToUpper(q.Base))
+	}
+	if q.Quote != "" {
+		query = query.Where("quote = ?", strings.ToUpper(q.Quote))
+	}
+	if q.From != nil {
+		query = query.Where("date >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("date <= ?", *q.To)
+	}
+
+	rates := []model.ExchangeRate{}
+	err := query.Order("base, quote, date DESC").Find(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// converter turns amounts in any currency into a user's home currency. It
+// loads every rate involving the home currency once, so converting many rows
+// costs a single query. Currencies without any usable rate are remembered so
+// callers can report them instead of silently mixing currencies.
+type converter struct {
+	home    string
+	table   *money.RateTable
+	missing map[string]bool
+}
+
+func loadConverter(db *gorm.DB, userID uint) (*converter, error) {
+	var user model.User
+	if err := db.Select("id, home_currency").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var stored []model.ExchangeRate
+	err := db.Where("user_id = ? AND (base = ? OR quote = ?)", userID, user.HomeCurrency, user.HomeCurrency).
+		Find(&stored).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]money.Rate, 0, len(stored))
+	for _, r := range stored {
+		value, err := money.ParseRate(r.Rate)
+		if err != nil {
+			continue
+		}
+		rates = append(rates, money.Rate{Date: r.Date, Base: r.Base, Quote: r.Quote, Value: value})
+	}
+
+	return &converter{
+		home:    user.HomeCurrency,
+		table:   money.NewRateTable(rates),
+		missing: map[string]bool{},
+	}, nil
+}
+
+// convert returns amount in the home currency using the rate nearest to date.
+// It reports false when no rate is known for currency.
+func (c *converter) convert(amount int64, currency string, date time.Time) (int64, bool) {
+	converted, err := c.table.Convert(amount, currency, c.home, date)
+	if err != nil {
+		c.missing[currency] = true
+		return 0, false
+	}
+	return converted, true
+}
+
+func (c *converter) missingRates() []string {
+	currencies := make([]string, 0, len(c.missing))
+	for currency := range c.missing {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// fromHome converts amount from the home currency into currency using the
+// rate nearest to date.
+func (c *converter) fromHome(amount int64, currency string, date time.Time) (int64, bool) {
+	converted, err := c.table.Convert(amount, c.home, currency, date)
+	if err != nil {
+		c.missing[currency] = true
+		return 0, false
+	}
+	return converted, true
+}
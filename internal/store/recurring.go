@@ -0,0 +1,479 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/rules"
+	"github.com/nelsonfrank/finance-tracker/internal/schedule"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recurringLockKey is the Postgres advisory lock held while posting due
+// recurring transactions, so only one API replica does it at a time.
+const recurringLockKey int64 = 0x66696e7472656331
+
+var ErrInvalidOccurrence = errors.New("date is not an upcoming occurrence")
+
+// Occurrence is one scheduled date of a recurring transaction.
+type Occurrence struct {
+	RecurringID uint      `json:"recurring_id"`
+	Date        time.Time `json:"date"`
+	AccountID   uint      `json:"account_id"`
+	CategoryID  *uint     `json:"category_id"`
+	Amount      int64     `json:"amount"`
+	Payee       string    `json:"payee"`
+}
+
+// RecurrenceRule returns the schedule of rt.
+func RecurrenceRule(rt *model.RecurringTransaction) schedule.Rule {
+	return schedule.Rule{
+		Frequency:       schedule.Frequency(rt.Frequency),
+		Interval:        rt.Interval,
+		Start:           rt.StartDate,
+		Until:           rt.EndDate,
+		ByMonthDay:      rt.ByMonthDay,
+		LastBusinessDay: rt.LastBusinessDay,
+	}
+}
+
+type RecurringStorage struct {
+	db *gorm.DB
+}
+
+func (s *RecurringStorage) Create(ctx context.Context, rt *model.RecurringTransaction) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkRecurring(tx, rt); err != nil {
+			return err
+		}
+
+		advanceNextDate(rt, rt.StartDate)
+
+		return tx.Create(rt).Error
+	})
+}
+
+func (s *RecurringStorage) GetByID(ctx context.Context, userID, recurringID uint) (*model.RecurringTransaction, error) {
+	return getRecurring(s.db.WithContext(ctx), userID, recurringID, false)
+}
+
+func (s *RecurringStorage) List(ctx context.Context, userID uint) ([]model.RecurringTransaction, error) {
+	rts := []model.RecurringTransaction{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("next_date NULLS LAST, id").
+		Find(&rts).Error
+
+	return rts, err
+}
+
+// Update saves a changed template. The next occurrence is recomputed from the
+// day after the last one already posted, so changing the schedule never
+// re-posts a date that has been dealt with.
+func (s *RecurringStorage) Update(ctx context.Context, rt *model.RecurringTransaction) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := getRecurring(tx, rt.UserID, rt.ID, true); err != nil {
+			return err
+		}
+		if err := checkRecurring(tx, rt); err != nil {
+			return err
+		}
+
+		var posted struct {
+			Last *time.Time
+		}
+		err := tx.Model(&model.Transaction{}).Unscoped().
+			Where("recurring_id = ?", rt.ID).
+			Select("MAX(occurrence_date) AS last").
+			Scan(&posted).Error
+		if err != nil {
+			return err
+		}
+
+		from := rt.StartDate
+		if posted.Last != nil && !posted.Last.Before(from) {
+			from = posted.Last.AddDate(0, 0, 1)
+		}
+		advanceNextDate(rt, from)
+
+		return tx.Model(rt).
+			Select("account_id", "category_id", "amount", "payee", "memo", "frequency", "interval",
+				"by_month_day", "last_business_day", "start_date", "end_date", "next_date").
+			Updates(rt).Error
+	})
+}
+
+func (s *RecurringStorage) Delete(ctx context.Context, userID, recurringID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", recurringID, userID).
+		Delete(&model.RecurringTransaction{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Upcoming lists the occurrences of all the user's recurring transactions in
+// [from, to] that are neither skipped nor already posted early.
+func (s *RecurringStorage) Upcoming(ctx context.Context, userID uint, from, to time.Time) ([]Occurrence, error) {
+	db := s.db.WithContext(ctx)
+
+	var rts []model.RecurringTransaction
+	if err := db.Where("user_id = ? AND next_date IS NOT NULL", userID).Find(&rts).Error; err != nil {
+		return nil, err
+	}
+
+	var posted []struct {
+		RecurringID    uint
+		OccurrenceDate time.Time
+	}
+	err := db.Model(&model.Transaction{}).Unscoped().
+		Select("recurring_id, occurrence_date").
+		Where("user_id = ? AND recurring_id IS NOT NULL AND occurrence_date >= ?", userID, from).
+		Scan(&posted).Error
+	if err != nil {
+		return nil, err
+	}
+	done := map[uint]map[string]bool{}
+	for _, p := range posted {
+		if done[p.RecurringID] == nil {
+			done[p.RecurringID] = map[string]bool{}
+		}
+		done[p.RecurringID][p.OccurrenceDate.Format(dateLayout)] = true
+	}
+
+	occurrences := []Occurrence{}
+	for _, rt := range rts {
+		start := from
+		if rt.NextDate.After(start) {
+			start = *rt.NextDate
+		}
+
+		for _, d := range RecurrenceRule(&rt).Between(start, to) {
+			key := d.Format(dateLayout)
+			if slices.Contains(rt.SkippedDates, key) || done[rt.ID][key] {
+				continue
+			}
+			occurrences = append(occurrences, Occurrence{
+				RecurringID: rt.ID,
+				Date:        d,
+				AccountID:   rt.AccountID,
+				CategoryID:  rt.CategoryID,
+				Amount:      rt.Amount,
+				Payee:       rt.Payee,
+			})
+		}
+	}
+
+	slices.SortStableFunc(occurrences, func(a, b Occurrence) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	return occurrences, nil
+}
+
+// Skip marks one upcoming occurrence so it is never posted.
+func (s *RecurringStorage) Skip(ctx context.Context, userID, recurringID uint, date time.Time) (*model.RecurringTransaction, error) {
+	var rt *model.RecurringTransaction
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if rt, err = getRecurring(tx, userID, recurringID, true); err != nil {
+			return err
+		}
+		if !isUpcoming(rt, date) {
+			return ErrInvalidOccurrence
+		}
+
+		rt.SkippedDates = append(rt.SkippedDates, date.Format(dateLayout))
+		if date.Equal(*rt.NextDate) {
+			advanceNextDate(rt, date.AddDate(0, 0, 1))
+		}
+
+		return tx.Model(rt).Select("skipped_dates", "next_date").Updates(rt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+// PostEarly posts the occurrence due on date right away, dated today. The
+// scheduler will not post it again when the date arrives.
+func (s *RecurringStorage) PostEarly(ctx context.Context, userID, recurringID uint, date, today time.Time) (*model.Transaction, error) {
+	var txn *model.Transaction
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rt, err := getRecurring(tx, userID, recurringID, true)
+		if err != nil {
+			return err
+		}
+		if !isUpcoming(rt, date) {
+			return ErrInvalidOccurrence
+		}
+
+		engine, err := loadRuleEngine(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		txn, err = postOccurrence(tx, engine, rt, date, today)
+		if err != nil {
+			return err
+		}
+		if txn == nil {
+			return ErrConflict
+		}
+
+		if date.Equal(*rt.NextDate) {
+			advanceNextDate(rt, date.AddDate(0, 0, 1))
+			return tx.Model(rt).Select("next_date").Updates(rt).Error
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txn, nil
+}
+
+// PostDue posts every occurrence due on or before today and returns how many
+// transactions were created. It is a no-op when another process already holds
+// the scheduler lock, and posting an occurrence that already exists is
+// silently skipped, so it is safe to call concurrently and repeatedly.
+//
+// Each template is posted under its own savepoint: one that fails is left
+// for the next tick without holding back the others, and its error is
+// returned together with the count. Templates whose account has been deleted
+// have their schedule ended, and a long backlog is posted over several calls.
+func (s *RecurringStorage) PostDue(ctx context.Context, today time.Time) (int, error) {
+	posted := 0
+	var failed []error
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", recurringLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var due []model.RecurringTransaction
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("next_date <= ?", today).
+			Order("user_id, id").
+			Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		engines := map[uint]*rules.Engine{}
+		for i := range due {
+			rt := &due[i]
+
+			var live int64
+			err := tx.Model(&model.Account{}).
+				Where("id = ? AND user_id = ?", rt.AccountID, rt.UserID).
+				Count(&live).Error
+			if err != nil {
+				return err
+			}
+			if live == 0 {
+				if err := endSchedule(tx, "id = ?", rt.ID); err != nil {
+					return err
+				}
+				continue
+			}
+
+			n := 0
+			err = tx.Transaction(func(tx *gorm.DB) error {
+				engine, ok := engines[rt.UserID]
+				if !ok {
+					var err error
+					if engine, err = loadRuleEngine(tx, rt.UserID); err != nil {
+						return err
+					}
+					engines[rt.UserID] = engine
+				}
+
+				dates, resume := dueOccurrences(rt, today)
+				for _, d := range dates {
+					txn, err := postOccurrence(tx, engine, rt, d, d)
+					if err != nil {
+						return err
+					}
+					if txn != nil {
+						n++
+					}
+				}
+
+				advanceNextDate(rt, resume)
+				return tx.Model(rt).Select("next_date").Updates(rt).Error
+			})
+			if err != nil {
+				failed = append(failed, fmt.Errorf("recurring transaction %d: %w", rt.ID, err))
+				continue
+			}
+
+			posted += n
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return posted, errors.Join(failed...)
+}
+
+// dueOccurrences returns the occurrences of rt from its next date up to
+// today that have not been skipped, and the date the schedule resumes from.
+// Between caps how many dates it returns, so when a long backlog is cut short
+// the schedule resumes after the last date returned and the rest is posted
+// on the following ticks.
+func dueOccurrences(rt *model.RecurringTransaction, today time.Time) ([]time.Time, time.Time) {
+	rule := RecurrenceRule(rt)
+	dates := rule.Between(*rt.NextDate, today)
+
+	resume := today.AddDate(0, 0, 1)
+	if len(dates) > 0 {
+		after := dates[len(dates)-1].AddDate(0, 0, 1)
+		if next, ok := rule.Next(after); ok && !next.After(today) {
+			resume = after
+		}
+	}
+
+	dates = slices.DeleteFunc(dates, func(d time.Time) bool {
+		return slices.Contains(rt.SkippedDates, d.Format(dateLayout))
+	})
+
+	return dates, resume
+}
+
+// postOccurrence creates the transaction for the occurrence of rt on
+// occurrence, dated postDate. It returns nil without error when that
+// occurrence has been posted before.
+func postOccurrence(tx *gorm.DB, engine *rules.Engine, rt *model.RecurringTransaction, occurrence, postDate time.Time) (*model.Transaction, error) {
+	txn := &model.Transaction{
+		UserID:         rt.UserID,
+		AccountID:      rt.AccountID,
+		CategoryID:     rt.CategoryID,
+		Amount:         rt.Amount,
+		Date:           postDate,
+		Payee:          rt.Payee,
+		Memo:           rt.Memo,
+		RecurringID:    &rt.ID,
+		OccurrenceDate: &occurrence,
+	}
+	out := engine.Apply(txn, false)
+
+	result := tx.Clauses(onConflictDoNothing).Create(txn)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	if err := adjustBalance(tx, txn.UserID, txn.AccountID, txn.Amount); err != nil {
+		return nil, err
+	}
+	if err := attachTags(tx, txn.UserID, map[uint][]string{txn.ID: out.AddTags}); err != nil {
+		return nil, err
+	}
+
+	return txn, nil
+}
+
+// endSchedule stops the templates matching query from being posted again.
+func endSchedule(tx *gorm.DB, query string, args ...any) error {
+	return tx.Model(&model.RecurringTransaction{}).
+		Where(query, args...).
+		Update("next_date", nil).Error
+}
+
+// advanceNextDate sets rt.NextDate to the first occurrence on or after from
+// that has not been skipped, or nil once the schedule has ended.
+func advanceNextDate(rt *model.RecurringTransaction, from time.Time) {
+	rule := RecurrenceRule(rt)
+	for {
+		next, ok := rule.Next(from)
+		if !ok {
+			rt.NextDate = nil
+			return
+		}
+		if !slices.Contains(rt.SkippedDates, next.Format(dateLayout)) {
+			rt.NextDate = &next
+			return
+		}
+		from = next.AddDate(0, 0, 1)
+	}
+}
+
+// isUpcoming reports whether date is a future occurrence of rt that has not
+// been posted by the scheduler or skipped.
+func isUpcoming(rt *model.RecurringTransaction, date time.Time) bool {
+	if rt.NextDate == nil || date.Before(*rt.NextDate) {
+		return false
+	}
+	if slices.Contains(rt.SkippedDates, date.Format(dateLayout)) {
+		return false
+	}
+
+	next, ok := RecurrenceRule(rt).Next(date)
+	return ok && next.Equal(date)
+}
+
+func getRecurring(db *gorm.DB, userID, recurringID uint, lock bool) (*model.RecurringTransaction, error) {
+	if lock {
+		db = db.Clauses(lockForUpdate)
+	}
+
+	var rt model.RecurringTransaction
+	err := db.Where("id = ? AND user_id = ?", recurringID, userID).First(&rt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &rt, nil
+}
+
+// checkRecurring validates the schedule and makes sure the account and
+// category belong to the same user.
+func checkRecurring(tx *gorm.DB, rt *model.RecurringTransaction) error {
+	if err := RecurrenceRule(rt).Validate(); err != nil {
+		return err
+	}
+
+	if err := checkTransactionCategory(tx, rt.UserID, rt.CategoryID); err != nil {
+		return err
+	}
+
+	var count int64
+	err := tx.Model(&model.Account{}).
+		Where("id = ? AND user_id = ?", rt.AccountID, rt.UserID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDueOccurrences(t *testing.T) {
+	tests := []struct {
+		name   string
+		rt     model.RecurringTransaction
+		next   string
+		today  string
+		count  int
+		first  string
+		last   string
+		resume string
+	}{
+		{
+			name:   "monthly caught up",
+			rt:     model.RecurringTransaction{Frequency: "monthly", Interval: 1, StartDate: date("2026-01-05")},
+			next:   "2026-02-05",
+			today:  "2026-04-10",
+			count:  3,
+			first:  "2026-02-05",
+			last:   "2026-04-05",
+			resume: "2026-04-11",
+		},
+		{
+			name:   "skipped dates are left out",
+			rt:     model.RecurringTransaction{Frequency: "monthly", Interval: 1, StartDate: date("2026-01-05"), SkippedDates: []string{"2026-03-05"}},
+			next:   "2026-02-05",
+			today:  "2026-04-10",
+			count:  2,
+			first:  "2026-02-05",
+			last:   "2026-04-05",
+			resume: "2026-04-11",
+		},
+		{
+			name:   "long daily backlog resumes after the cap",
+			rt:     model.RecurringTransaction{Frequency: "daily", Interval: 1, StartDate: date("2020-01-01")},
+			next:   "2020-01-01",
+			today:  "2026-01-01",
+			count:  1000,
+			first:  "2020-01-01",
+			last:   "2022-09-26",
+			resume: "2022-09-27",
+		},
+		{
+			name:   "backlog of exactly the cap",
+			rt:     model.RecurringTransaction{Frequency: "daily", Interval: 1, StartDate: date("2020-01-01")},
+			next:   "2020-01-01",
+			today:  "2022-09-26",
+			count:  1000,
+			first:  "2020-01-01",
+			last:   "2022-09-26",
+			resume: "2022-09-27",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := date(tt.next)
+			tt.rt.NextDate = &next
+
+			dates, resume := dueOccurrences(&tt.rt, date(tt.today))
+			if len(dates) != tt.count {
+				t.Fatalf("dueOccurrences() returned %d dates, want %d", len(dates), tt.count)
+			}
+			if !dates[0].Equal(date(tt.first)) || !dates[len(dates)-1].Equal(date(tt.last)) {
+				t.Errorf("dates run %s to %s, want %s to %s",
+					dates[0].Format(time.DateOnly), dates[len(dates)-1].Format(time.DateOnly), tt.first, tt.last)
+			}
+			if !resume.Equal(date(tt.resume)) {
+				t.Errorf("resume = %s, want %s", resume.Format(time.DateOnly), tt.resume)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+// ErrTokenReused is returned when a refresh token that was already rotated
+// is presented again. Its whole session has been revoked by then.
+var ErrTokenReused = errors.New("refresh token reused")
+
+type RefreshTokensStorage struct {
+	db *gorm.DB
+}
+
+// Create starts a new session for the user of t, from the device t was issued
+// to, and stores t as its first token.
+func (s *RefreshTokensStorage) Create(ctx context.Context, t *model.RefreshToken) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session := model.Session{
+			UserID:     t.UserID,
+			UserAgent:  t.UserAgent,
+			IP:         t.IP,
+			ExpiresAt:  t.ExpiresAt,
+			LastUsedAt: t.CreatedAt,
+			CreatedAt:  t.CreatedAt,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		t.SessionID = session.ID
+		return tx.Create(t).Error
+	})
+}
+
+// Rotate exchanges the token with the given hash for next, which joins the
+// same session and user; next gets their ids even when the token was reused.
+// The session is extended and takes the device of next. Unknown, expired and
+// revoked tokens fail with ErrNotFound; a token that was already rotated
+// revokes its session and fails with ErrTokenReused.
+func (s *RefreshTokensStorage) Rotate(ctx context.Context, tokenHash string, next *model.RefreshToken, now time.Time) error {
+	reused := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.RefreshToken
+		err := tx.Clauses(lockForUpdate).
+			Where("token_hash = ?", tokenHash).
+			First(&current).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if current.RevokedAt != nil || !current.ExpiresAt.After(now) {
+			return ErrNotFound
+		}
+
+		next.UserID = current.UserID
+		next.SessionID = current.SessionID
+
+		if current.RotatedAt != nil {
+			// Committed rather than rolled back: the session has to stay
+			// revoked even though the refresh fails.
+			reused = true
+			return revokeSessions(tx, now, "id = ?", current.SessionID)
+		}
+
+		err = tx.Model(&current).Update("rotated_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.Session{}).
+			Where("id = ?", current.SessionID).
+			Updates(map[string]any{
+				"user_agent":   next.UserAgent,
+				"ip":           next.IP,
+				"expires_at":   next.ExpiresAt,
+				"last_used_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(next).Error
+	})
+	if err != nil {
+		return err
+	}
+	if reused {
+		return ErrTokenReused
+	}
+
+	return nil
+}
+
+// Revoke revokes the session of the token with the given hash, which signs
+// the device it belongs to out. Unknown tokens are ignored.
+func (s *RefreshTokensStorage) Revoke(ctx context.Context, tokenHash string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var t model.RefreshToken
+		err := tx.Where("token_hash = ?", tokenHash).First(&t).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		return revokeSessions(tx, now, "id = ?", t.SessionID)
+	})
+}
+
+// RevokeAll revokes every session of the user.
+func (s *RefreshTokensStorage) RevokeAll(ctx context.Context, userID uint, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, now, "user_id = ?", userID)
+	})
+}
@@ -0,0 +1,361 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/budget"
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+// ReportQuery selects the period a report covers and how it is bucketed and
+// grouped. From and To are inclusive. GroupBy is ignored by reports that are
+// not grouped, such as net worth. TagID limits the report to transactions
+// with that tag.
+type ReportQuery struct {
+	From      time.Time `json:"from" validate:"required"`
+	To        time.Time `json:"to" validate:"required,gtefield=From"`
+	Interval  string    `json:"interval" validate:"oneof=day week month quarter year"`
+	GroupBy   string    `json:"group_by" validate:"omitempty,oneof=category category_tree payee account tag"`
+	AccountID *uint     `json:"account_id"`
+	TagID     *uint     `json:"tag_id"`
+}
+
+// ReportAmounts are the income and expense of a group, both positive, and
+// their difference.
+type ReportAmounts struct {
+	Income  int64 `json:"income"`
+	Expense int64 `json:"expense"`
+	Net     int64 `json:"net"`
+}
+
+func (a *ReportAmounts) add(income, expense int64) {
+	a.Income += income
+	a.Expense += expense
+	a.Net = a.Income - a.Expense
+}
+
+// ReportLine is one group of a report. Key identifies the group (a category,
+// account or tag id, or the payee itself) and is empty for uncategorised
+// transactions. ParentKey is only set when grouping by category tree, where
+// each category also includes everything recorded against its descendants.
+type ReportLine struct {
+	Key       string  `json:"key"`
+	Label     string  `json:"label"`
+	ParentKey *string `json:"parent_key,omitempty"`
+	ReportAmounts
+}
+
+type ReportBucket struct {
+	Period time.Time    `json:"period"`
+	Lines  []ReportLine `json:"lines"`
+}
+
+// ReportTotal is a group's total over the whole report range next to its
+// total over the previous period of the same length.
+type ReportTotal struct {
+	ReportLine
+	Previous ReportAmounts `json:"previous"`
+	Change   ReportAmounts `json:"change"`
+}
+
+// Report holds income and expense in the user's home currency, bucketed by
+// Interval and grouped by GroupBy. Transfers are excluded and split
+// transactions count line by line. When grouping by tag, a transaction counts
+// towards each of its tags and untagged transactions are left out.
+type Report struct {
+	HomeCurrency string         `json:"home_currency"`
+	Interval     string         `json:"interval"`
+	GroupBy      string         `json:"group_by"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	PreviousFrom time.Time      `json:"previous_from"`
+	PreviousTo   time.Time      `json:"previous_to"`
+	Buckets      []ReportBucket `json:"buckets"`
+	Totals       []ReportTotal  `json:"totals"`
+	MissingRates []string       `json:"missing_rates"`
+}
+
+// reportGroupings maps each GroupBy value to the SQL producing the group key
+// and label, and any join it needs. Only these fixed fragments are ever
+// interpolated into the report query.
+var reportGroupings = map[string]struct {
+	key, label, join string
+}{
+	"category": {
+		key:   "COALESCE(t.category_id::text, '')",
+		label: "COALESCE(c.name, '')",
+		join:  "LEFT JOIN categories c ON c.id = t.category_id",
+	},
+	"category_tree": {
+		key:   "COALESCE(t.category_id::text, '')",
+		label: "COALESCE(c.name, '')",
+		join:  "LEFT JOIN categories c ON c.id = t.category_id",
+	},
+	"payee": {
+		key:   "t.payee",
+		label: "t.payee",
+	},
+	"account": {
+		key:   "a.id::text",
+		label: "a.name",
+	},
+	"tag": {
+		key:   "g.id::text",
+		label: "g.name",
+		join:  "JOIN transaction_tags tt ON tt.transaction_id = t.id JOIN tags g ON g.id = tt.tag_id AND g.deleted_at IS NULL",
+	},
+}
+
+type ReportsStorage struct {
+	db *gorm.DB
+}
+
+// CashFlow builds a Report. Aggregation happens in Postgres with date_trunc;
+// only one row per bucket, group, currency and (for foreign currencies) day
+// comes back, so that each can be converted at the rate nearest to it.
+func (s *ReportsStorage) CashFlow(ctx context.Context, userID uint, q ReportQuery) (*Report, error) {
+	db := s.db.WithContext(ctx)
+
+	grouping, ok := reportGroupings[q.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown report grouping %q", q.GroupBy)
+	}
+
+	conv, err := loadConverter(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prevFrom, prevTo := previousPeriod(q.From, q.To)
+
+	args := []any{q.Interval, q.From, conv.home, q.Interval, userID, prevFrom, q.To}
+	filters := ""
+	if q.AccountID != nil {
+		filters += " AND t.account_id = ?"
+		args = append(args, *q.AccountID)
+	}
+	if q.TagID != nil {
+		filters += " AND t.id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id = ?)"
+		args = append(args, *q.TagID)
+	}
+
+	var rows []struct {
+		Period   time.Time
+		Current  bool
+		Key      string
+		Label    string
+		Currency string
+		RateDate time.Time
+		Income   int64
+		Expense  int64
+	}
+	err = db.Raw(fmt.Sprintf(`
+		SELECT date_trunc(?, t.date::timestamp)::date AS period,
+			t.date >= ? AS current,
+			%s AS key, %s AS label, a.currency,
+			CASE WHEN a.currency = ? THEN date_trunc(?, t.date::timestamp)::date ELSE t.date END AS rate_date,
+			COALESCE(SUM(t.amount) FILTER (WHERE t.amount > 0), 0) AS income,
+			COALESCE(-SUM(t.amount) FILTER (WHERE t.amount < 0), 0) AS expense
+		FROM %s t
+		JOIN accounts a ON a.id = t.account_id
+		%s
+		WHERE t.user_id = ? AND NOT t.is_transfer
+			AND t.date >= ? AND t.date <= ?%s
+		GROUP BY 1, 2, 3, 4, 5, 6`, grouping.key, grouping.label, ledgerLines, grouping.join, filters), args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	buckets := map[time.Time]map[string]*ReportAmounts{}
+	current := map[string]*ReportAmounts{}
+	previous := map[string]*ReportAmounts{}
+	for _, row := range rows {
+		income, iOK := conv.convert(row.Income, row.Currency, row.RateDate)
+		expense, eOK := conv.convert(row.Expense, row.Currency, row.RateDate)
+		if !iOK || !eOK {
+			continue
+		}
+		labels[row.Key] = row.Label
+
+		if !row.Current {
+			amountsFor(previous, row.Key).add(income, expense)
+			continue
+		}
+
+		period := row.Period
+		if buckets[period] == nil {
+			buckets[period] = map[string]*ReportAmounts{}
+		}
+		amountsFor(buckets[period], row.Key).add(income, expense)
+		amountsFor(current, row.Key).add(income, expense)
+	}
+
+	var parents map[string]*string
+	if q.GroupBy == "category_tree" {
+		tree, err := loadCategoryTree(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		for key, name := range tree.labels {
+			labels[key] = name
+		}
+		tree.rollup(current)
+		tree.rollup(previous)
+		for _, groups := range buckets {
+			tree.rollup(groups)
+		}
+		parents = tree.parentKeys
+	}
+
+	report := &Report{
+		HomeCurrency: conv.home,
+		Interval:     q.Interval,
+		GroupBy:      q.GroupBy,
+		From:         q.From,
+		To:           q.To,
+		PreviousFrom: prevFrom,
+		PreviousTo:   prevTo,
+		Buckets:      []ReportBucket{},
+		Totals:       []ReportTotal{},
+	}
+
+	for period, groups := range buckets {
+		report.Buckets = append(report.Buckets, ReportBucket{
+			Period: period,
+			Lines:  reportLines(groups, labels, parents),
+		})
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool {
+		return report.Buckets[i].Period.Before(report.Buckets[j].Period)
+	})
+
+	keys := map[string]*ReportAmounts{}
+	for key, a := range current {
+		keys[key] = a
+	}
+	for key := range previous {
+		amountsFor(keys, key)
+	}
+	for _, line := range reportLines(keys, labels, parents) {
+		total := ReportTotal{ReportLine: line}
+		if p, ok := previous[line.Key]; ok {
+			total.Previous = *p
+		}
+		total.Change = ReportAmounts{
+			Income:  total.Income - total.Previous.Income,
+			Expense: total.Expense - total.Previous.Expense,
+			Net:     total.Net - total.Previous.Net,
+		}
+		report.Totals = append(report.Totals, total)
+	}
+
+	report.MissingRates = conv.missingRates()
+
+	return report, nil
+}
+
+// previousPeriod returns the range of the same length that ends the day
+// before from. Ranges made of whole calendar months are shifted by months so
+// that e.g. March is compared with February rather than with the 31 days
+// before it.
+func previousPeriod(from, to time.Time) (time.Time, time.Time) {
+	end := to.AddDate(0, 0, 1)
+	if from.Equal(budget.MonthStart(from)) && end.Equal(budget.MonthStart(end)) {
+		months := (end.Year()-from.Year())*12 + int(end.Month()-from.Month())
+		return from.AddDate(0, -months, 0), from.AddDate(0, 0, -1)
+	}
+
+	days := int(end.Sub(from).Hours() / 24)
+	return from.AddDate(0, 0, -days), from.AddDate(0, 0, -1)
+}
+
+func amountsFor(m map[string]*ReportAmounts, key string) *ReportAmounts {
+	a, ok := m[key]
+	if !ok {
+		a = &ReportAmounts{}
+		m[key] = a
+	}
+	return a
+}
+
+// reportLines flattens groups into lines, largest expense first.
+func reportLines(groups map[string]*ReportAmounts, labels map[string]string, parents map[string]*string) []ReportLine {
+	lines := make([]ReportLine, 0, len(groups))
+	for key, a := range groups {
+		lines = append(lines, ReportLine{
+			Key:           key,
+			Label:         labels[key],
+			ParentKey:     parents[key],
+			ReportAmounts: *a,
+		})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Expense != lines[j].Expense {
+			return lines[i].Expense > lines[j].Expense
+		}
+		if lines[i].Income != lines[j].Income {
+			return lines[i].Income > lines[j].Income
+		}
+		return lines[i].Key < lines[j].Key
+	})
+	return lines
+}
+
+// categoryTree is a user's category hierarchy keyed the way report lines are.
+type categoryTree struct {
+	parents    map[uint]*uint
+	labels     map[string]string
+	parentKeys map[string]*string
+}
+
+func loadCategoryTree(db *gorm.DB, userID uint) (*categoryTree, error) {
+	var categories []model.Category
+	if err := db.Select("id, parent_id, name").Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{
+		parents:    make(map[uint]*uint, len(categories)),
+		labels:     make(map[string]string, len(categories)),
+		parentKeys: make(map[string]*string, len(categories)),
+	}
+	for _, c := range categories {
+		key := strconv.FormatUint(uint64(c.ID), 10)
+		tree.parents[c.ID] = c.ParentID
+		tree.labels[key] = c.Name
+		if c.ParentID != nil {
+			parent := strconv.FormatUint(uint64(*c.ParentID), 10)
+			tree.parentKeys[key] = &parent
+		}
+	}
+
+	return tree, nil
+}
+
+// rollup adds the amounts of every category in groups to all of its
+// ancestors. Uncategorised amounts are left as they are.
+func (t *categoryTree) rollup(groups map[string]*ReportAmounts) {
+	income := map[uint]int64{}
+	expense := map[uint]int64{}
+	for key, a := range groups {
+		id, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		income[uint(id)] = a.Income
+		expense[uint(id)] = a.Expense
+	}
+
+	expense = budget.Rollup(expense, t.parents)
+	for id, in := range budget.Rollup(income, t.parents) {
+		key := strconv.FormatUint(uint64(id), 10)
+		groups[key] = &ReportAmounts{Income: in, Expense: expense[id], Net: in - expense[id]}
+	}
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/rules"
+	"gorm.io/gorm"
+)
+
+// RuleChange is what applying a rule does, or would do, to one transaction.
+type RuleChange struct {
+	TransactionID uint           `json:"transaction_id"`
+	Before        RuleChangeSide `json:"before"`
+	After         RuleChangeSide `json:"after"`
+	AddTags       []string       `json:"add_tags,omitempty"`
+}
+
+type RuleChangeSide struct {
+	CategoryID *uint  `json:"category_id"`
+	Payee      string `json:"payee"`
+	IsTransfer bool   `json:"is_transfer"`
+}
+
+type RulesStorage struct {
+	db *gorm.DB
+}
+
+func (s *RulesStorage) Create(ctx context.Context, rule *model.Rule) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkRule(tx, rule); err != nil {
+			return err
+		}
+
+		return tx.Create(rule).Error
+	})
+}
+
+func (s *RulesStorage) GetByID(ctx context.Context, userID, ruleID uint) (*model.Rule, error) {
+	var rule model.Rule
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", ruleID, userID).
+		First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (s *RulesStorage) List(ctx context.Context, userID uint) ([]model.Rule, error) {
+	rs := []model.Rule{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("priority, id").
+		Find(&rs).Error
+
+	return rs, err
+}
+
+func (s *RulesStorage) Update(ctx context.Context, rule *model.Rule) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkRule(tx, rule); err != nil {
+			return err
+		}
+
+		result := tx.Model(rule).
+			Where("user_id = ?", rule.UserID).
+			Select("*").
+			Omit("id", "user_id", "created_at", "deleted_at").
+			Updates(rule)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (s *RulesStorage) Delete(ctx context.Context, userID, ruleID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", ruleID, userID).
+		Delete(&model.Rule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Apply runs a single rule over the user's existing transactions that match
+// the filters in q, overriding categories they already have. With dryRun set
+// nothing is written and the returned changes are only a preview.
+func (s *RulesStorage) Apply(ctx context.Context, userID, ruleID uint, q TransactionQuery, dryRun bool) ([]RuleChange, error) {
+	changes := []RuleChange{}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rule model.Rule
+		err := tx.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		rule.Enabled = true
+		engine := rules.New([]model.Rule{rule})
+
+		query := applyTransactionFilters(tx.Where("user_id = ? AND transfer_peer_id IS NULL", userID), q)
+
+		var txns []model.Transaction
+		return query.Preload("Tags").FindInBatches(&txns, importInsertBatchSize, func(batch *gorm.DB, _ int) error {
+			tags := map[uint][]string{}
+
+			for i := range txns {
+				t := &txns[i]
+				before := RuleChangeSide{CategoryID: t.CategoryID, Payee: t.Payee, IsTransfer: t.IsTransfer}
+
+				out := engine.Apply(t, true)
+				if !out.Matched() {
+					continue
+				}
+				if t.IsSplit {
+					// Split lines carry their own categories, so only the
+					// payee and tags of a split transaction are changed.
+					t.CategoryID, t.IsTransfer = before.CategoryID, before.IsTransfer
+				}
+
+				newTags := missingTags(t.Tags, out.AddTags)
+				after := RuleChangeSide{CategoryID: t.CategoryID, Payee: t.Payee, IsTransfer: t.IsTransfer}
+				if sameSide(before, after) && len(newTags) == 0 {
+					continue
+				}
+
+				changes = append(changes, RuleChange{
+					TransactionID: t.ID,
+					Before:        before,
+					After:         after,
+					AddTags:       newTags,
+				})
+
+				if dryRun {
+					continue
+				}
+
+				err := tx.Model(t).
+					Select("category_id", "payee", "is_transfer").
+					Updates(t).Error
+				if err != nil {
+					return err
+				}
+				if len(newTags) > 0 {
+					tags[t.ID] = newTags
+				}
+			}
+
+			return attachTags(tx, userID, tags)
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// loadRuleEngine compiles the user's enabled rules for use within tx.
+func loadRuleEngine(tx *gorm.DB, userID uint) (*rules.Engine, error) {
+	var rs []model.Rule
+	if err := tx.Where("user_id = ? AND enabled", userID).Find(&rs).Error; err != nil {
+		return nil, err
+	}
+
+	return rules.New(rs), nil
+}
+
+// checkRule validates the rule and makes sure the account and category it
+// refers to belong to the same user.
+func checkRule(tx *gorm.DB, rule *model.Rule) error {
+	if err := rules.Validate(*rule); err != nil {
+		return err
+	}
+
+	if err := checkTransactionCategory(tx, rule.UserID, rule.SetCategoryID); err != nil {
+		return err
+	}
+
+	if rule.AccountID != nil {
+		var count int64
+		err := tx.Model(&model.Account{}).
+			Where("id = ? AND user_id = ?", *rule.AccountID, rule.UserID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+	}
+
+	return nil
+}
+
+func missingTags(have []model.Tag, want []string) []string {
+	existing := make(map[string]bool, len(have))
+	for _, t := range have {
+		existing[strings.ToLower(t.Name)] = true
+	}
+
+	var missing []string
+	for _, n := range want {
+		if !existing[strings.ToLower(n)] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+func sameSide(a, b RuleChangeSide) bool {
+	sameCategory := (a.CategoryID == nil && b.CategoryID == nil) ||
+		(a.CategoryID != nil && b.CategoryID != nil && *a.CategoryID == *b.CategoryID)
+
+	return sameCategory && a.Payee == b.Payee && a.IsTransfer == b.IsTransfer
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+type SessionsStorage struct {
+	db *gorm.DB
+}
+
+// List returns the user's sessions that are neither revoked nor expired,
+// most recently used first.
+func (s *SessionsStorage) List(ctx context.Context, userID uint, now time.Time) ([]model.Session, error) {
+	sessions := []model.Session{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC, id DESC").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+// GetActive returns the session if it is neither revoked nor expired.
+func (s *SessionsStorage) GetActive(ctx context.Context, userID, sessionID uint, now time.Time) (*model.Session, error) {
+	var session model.Session
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Touch records that the session was used at now.
+func (s *SessionsStorage) Touch(ctx context.Context, sessionID uint, now time.Time) error {
+	return s.db.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id = ?", sessionID).
+		UpdateColumn("last_used_at", now).Error
+}
+
+// Revoke signs the device of one of the user's active sessions out.
+func (s *SessionsStorage) Revoke(ctx context.Context, userID, sessionID uint, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.Session{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		return revokeSessions(tx, now, "id = ?", sessionID)
+	})
+}
+
+// RevokeOthers signs out every device of the user except the one of the
+// given session and returns how many sessions were revoked.
+func (s *SessionsStorage) RevokeOthers(ctx context.Context, userID, keepID uint, now time.Time) (int64, error) {
+	var revoked int64
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?", userID, keepID, now).
+			Count(&revoked).Error
+		if err != nil {
+			return err
+		}
+
+		return revokeSessions(tx, now, "user_id = ? AND id <> ?", userID, keepID)
+	})
+
+	return revoked, err
+}
+
+// revokeSessions revokes the sessions matching query together with their
+// refresh tokens.
+func revokeSessions(tx *gorm.DB, now time.Time, query string, args ...any) error {
+	var ids []uint
+	err := tx.Model(&model.Session{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err = tx.Model(&model.Session{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&model.RefreshToken{}).
+		Where("session_id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", now).Error
+}
@@ -2,22 +2,207 @@ package store
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"github.com/nelsonfrank/finance-tracker/internal/forecast"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrNotFound = errors.New("resource not found")
+	ErrConflict = errors.New("resource already exists")
+)
+
+// lockForUpdate is used when a row has to be read and then written back
+// within the same transaction, e.g. when adjusting an account balance.
+var lockForUpdate = clause.Locking{Strength: "UPDATE"}
+
+var onConflictDoNothing = clause.OnConflict{DoNothing: true}
+
 type Storage struct {
 	Posts interface {
 		Create(context.Context, *Post) error
 	}
 	Users interface {
-		Create(context.Context, *User) error
+		Create(context.Context, *model.User) error
+		CreateAndInvite(ctx context.Context, user *model.User, tokenHash string, exp time.Duration) error
+		Invite(ctx context.Context, userID uint, tokenHash string, exp time.Duration) error
+		LastInvitedAt(ctx context.Context, userID uint) (time.Time, error)
+		Activate(ctx context.Context, tokenHash string, now time.Time) error
+		RequestPasswordReset(ctx context.Context, userID uint, tokenHash string, exp time.Duration) error
+		LastPasswordResetAt(ctx context.Context, userID uint) (time.Time, error)
+		ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) error
+		Delete(ctx context.Context, userID uint) error
+		SetHomeCurrency(ctx context.Context, userID uint, currency string) error
+	}
+	RefreshTokens interface {
+		Create(context.Context, *model.RefreshToken) error
+		Rotate(ctx context.Context, tokenHash string, next *model.RefreshToken, now time.Time) error
+		Revoke(ctx context.Context, tokenHash string, now time.Time) error
+		RevokeAll(ctx context.Context, userID uint, now time.Time) error
+	}
+	MFA interface {
+		StartTOTP(ctx context.Context, userID uint, secret string) error
+		EnableTOTP(ctx context.Context, userID uint, step int64, codeHashes []string, now time.Time) error
+		DisableTOTP(ctx context.Context, userID uint) error
+		UseTOTPStep(ctx context.Context, userID uint, step int64) error
+		UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) error
+		ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string, now time.Time) error
+		RecoveryCodesLeft(ctx context.Context, userID uint) (int64, error)
+		CreateChallenge(context.Context, *model.MFAChallenge) error
+		AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int, now time.Time) (*model.MFAChallenge, error)
+		DeleteChallenge(ctx context.Context, challengeID uint, now time.Time) error
+	}
+	Sessions interface {
+		List(ctx context.Context, userID uint, now time.Time) ([]model.Session, error)
+		GetActive(ctx context.Context, userID, sessionID uint, now time.Time) (*model.Session, error)
+		Touch(ctx context.Context, sessionID uint, now time.Time) error
+		Revoke(ctx context.Context, userID, sessionID uint, now time.Time) error
+		RevokeOthers(ctx context.Context, userID, keepID uint, now time.Time) (int64, error)
+	}
+	Accounts interface {
+		Create(context.Context, *model.Account) error
+		GetByID(ctx context.Context, userID, accountID uint) (*model.Account, error)
+		List(ctx context.Context, userID uint) ([]model.Account, error)
+		Update(context.Context, *model.Account) error
+		Delete(ctx context.Context, userID, accountID uint) error
+	}
+	Transactions interface {
+		Create(context.Context, *model.Transaction) error
+		GetByID(ctx context.Context, userID, transactionID uint) (*model.Transaction, error)
+		List(ctx context.Context, userID uint, q TransactionQuery) ([]model.Transaction, string, error)
+		Update(context.Context, *model.Transaction) error
+		Delete(ctx context.Context, userID, transactionID uint) ([]string, error)
+		CreateTransfer(context.Context, Transfer) (*model.Transaction, *model.Transaction, error)
+		SetSplits(ctx context.Context, userID, transactionID uint, splits []model.TransactionSplit) (*model.Transaction, error)
+	}
+	Attachments interface {
+		Create(ctx context.Context, a *model.Attachment, quota int64) error
+		GetByID(ctx context.Context, userID, transactionID, attachmentID uint) (*model.Attachment, error)
+		List(ctx context.Context, userID, transactionID uint) ([]model.Attachment, error)
+		Delete(ctx context.Context, userID, attachmentID uint) error
+		Usage(ctx context.Context, userID uint) (int64, error)
+	}
+	Categories interface {
+		Create(context.Context, *model.Category) error
+		GetByID(ctx context.Context, userID, categoryID uint) (*model.Category, error)
+		List(ctx context.Context, userID uint) ([]model.Category, error)
+		Update(context.Context, *model.Category) error
+		Delete(ctx context.Context, userID, categoryID, replacementID uint) error
+	}
+	Tags interface {
+		Create(context.Context, *model.Tag) error
+		GetByID(ctx context.Context, userID, tagID uint) (*model.Tag, error)
+		List(ctx context.Context, userID uint) ([]model.Tag, error)
+		Update(context.Context, *model.Tag) error
+		Delete(ctx context.Context, userID, tagID uint) error
+		Tag(ctx context.Context, userID uint, q TransactionQuery, names []string) (int64, error)
+		Untag(ctx context.Context, userID uint, q TransactionQuery, names []string) (int64, error)
+	}
+	Budgets interface {
+		Create(context.Context, *model.Budget) error
+		GetByID(ctx context.Context, userID, budgetID uint) (*model.Budget, error)
+		List(ctx context.Context, userID uint, month *time.Time) ([]model.Budget, error)
+		Update(context.Context, *model.Budget) error
+		Delete(ctx context.Context, userID, budgetID uint) error
+		Statuses(ctx context.Context, userID uint, month time.Time) ([]BudgetStatus, error)
+	}
+	Imports interface {
+		Commit(context.Context, *model.ImportBatch, []model.Transaction) error
+		ExistingFITIDs(ctx context.Context, userID, accountID uint, fitids []string) (map[string]bool, error)
+		GetByID(ctx context.Context, userID, batchID uint) (*model.ImportBatch, error)
+		List(ctx context.Context, userID uint) ([]model.ImportBatch, error)
+		Undo(ctx context.Context, userID, batchID uint) ([]string, error)
+	}
+	Duplicates interface {
+		Find(ctx context.Context, userID uint, windowDays int, minScore float64) ([]DuplicatePair, error)
+		Dismiss(ctx context.Context, userID, aID, bID uint) error
+		Merge(ctx context.Context, userID, keepID, removeID uint) (*model.Transaction, error)
+	}
+	Rules interface {
+		Create(context.Context, *model.Rule) error
+		GetByID(ctx context.Context, userID, ruleID uint) (*model.Rule, error)
+		List(ctx context.Context, userID uint) ([]model.Rule, error)
+		Update(context.Context, *model.Rule) error
+		Delete(ctx context.Context, userID, ruleID uint) error
+		Apply(ctx context.Context, userID, ruleID uint, q TransactionQuery, dryRun bool) ([]RuleChange, error)
+	}
+	Recurring interface {
+		Create(context.Context, *model.RecurringTransaction) error
+		GetByID(ctx context.Context, userID, recurringID uint) (*model.RecurringTransaction, error)
+		List(ctx context.Context, userID uint) ([]model.RecurringTransaction, error)
+		Update(context.Context, *model.RecurringTransaction) error
+		Delete(ctx context.Context, userID, recurringID uint) error
+		Upcoming(ctx context.Context, userID uint, from, to time.Time) ([]Occurrence, error)
+		Skip(ctx context.Context, userID, recurringID uint, date time.Time) (*model.RecurringTransaction, error)
+		PostEarly(ctx context.Context, userID, recurringID uint, date, today time.Time) (*model.Transaction, error)
+		PostDue(ctx context.Context, today time.Time) (int, error)
+	}
+	ExchangeRates interface {
+		Upsert(ctx context.Context, userID uint, rates []model.ExchangeRate) ([]model.ExchangeRate, error)
+		List(ctx context.Context, userID uint, q RateQuery) ([]model.ExchangeRate, error)
+	}
+	Reports interface {
+		CashFlow(ctx context.Context, userID uint, q ReportQuery) (*Report, error)
+	}
+	NetWorth interface {
+		SnapshotDue(ctx context.Context, date time.Time) (int, error)
+		Backfill(ctx context.Context, userID uint, through time.Time) (int, error)
+		Series(ctx context.Context, userID uint, from, to time.Time, interval string) (*NetWorthSeries, error)
+	}
+	Goals interface {
+		Create(context.Context, *model.Goal) error
+		GetByID(ctx context.Context, userID, goalID uint) (*model.Goal, error)
+		List(ctx context.Context, userID uint) ([]model.Goal, error)
+		Update(context.Context, *model.Goal) error
+		Delete(ctx context.Context, userID, goalID uint) error
+		Contribute(context.Context, *model.GoalContribution) error
+		Contributions(ctx context.Context, userID, goalID uint) ([]model.GoalContribution, error)
+		Progress(ctx context.Context, goals []model.Goal, today time.Time) ([]GoalProgress, error)
+		CheckAll(ctx context.Context, today time.Time) ([]GoalTransition, error)
+	}
+	Debts interface {
+		Create(context.Context, *model.Debt) error
+		GetByID(ctx context.Context, userID, debtID uint) (*model.Debt, error)
+		List(ctx context.Context, userID uint) ([]model.Debt, error)
+		Update(context.Context, *model.Debt) error
+		Delete(ctx context.Context, userID, debtID uint) error
+		Plan(ctx context.Context, userID uint, q PlanQuery, today time.Time) (*DebtPlan, error)
+	}
+	Forecast interface {
+		Project(ctx context.Context, userID uint, today time.Time, days int) (*forecast.Forecast, error)
+	}
+	Dashboard interface {
+		Summary(ctx context.Context, userID uint, now time.Time, recent int) (*DashboardSummary, error)
 	}
 }
 
 func NewStorage(db *gorm.DB) Storage {
 	return Storage{
-		Posts: &PostsStorage{db},
-		Users: &UsersStorage{db},
+		Posts:         &PostsStorage{db},
+		Users:         &UsersStorage{db},
+		RefreshTokens: &RefreshTokensStorage{db},
+		Sessions:      &SessionsStorage{db},
+		MFA:           &MFAStorage{db},
+		Accounts:      &AccountsStorage{db},
+		Transactions:  &TransactionsStorage{db},
+		Attachments:   &AttachmentsStorage{db},
+		Categories:    &CategoriesStorage{db},
+		Tags:          &TagsStorage{db},
+		Budgets:       &BudgetsStorage{db},
+		Imports:       &ImportsStorage{db},
+		Duplicates:    &DuplicatesStorage{db},
+		Rules:         &RulesStorage{db},
+		Recurring:     &RecurringStorage{db},
+		ExchangeRates: &ExchangeRatesStorage{db},
+		Reports:       &ReportsStorage{db},
+		NetWorth:      &NetWorthStorage{db},
+		Goals:         &GoalsStorage{db},
+		Debts:         &DebtsStorage{db},
+		Forecast:      &ForecastStorage{db},
+		Dashboard:     &DashboardStorage{db},
 	}
 }
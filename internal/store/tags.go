@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+type TagsStorage struct {
+	db *gorm.DB
+}
+
+func (s *TagsStorage) Create(ctx context.Context, tag *model.Tag) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTagName(tx, tag); err != nil {
+			return err
+		}
+
+		return tx.Create(tag).Error
+	})
+}
+
+func (s *TagsStorage) GetByID(ctx context.Context, userID, tagID uint) (*model.Tag, error) {
+	var tag model.Tag
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", tagID, userID).
+		First(&tag).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (s *TagsStorage) List(ctx context.Context, userID uint) ([]model.Tag, error) {
+	tags := []model.Tag{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("name, id").
+		Find(&tags).Error
+
+	return tags, err
+}
+
+// Update renames a tag. Renaming it to the name of another of the user's
+// tags is a conflict; changing only the case of its own name is not.
+func (s *TagsStorage) Update(ctx context.Context, tag *model.Tag) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTagName(tx, tag); err != nil {
+			return err
+		}
+
+		result := tx.Model(tag).
+			Where("user_id = ?", tag.UserID).
+			Select("name").
+			Updates(tag)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// Delete removes a tag from every transaction and then deletes it.
+func (s *TagsStorage) Delete(ctx context.Context, userID, tagID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", tagID, userID).Delete(&model.Tag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return tx.Exec("DELETE FROM transaction_tags WHERE tag_id = ?", tagID).Error
+	})
+}
+
+// Tag adds the named tags to every transaction matching the filters in q,
+// creating tags that do not exist yet. The cursor and limit of q are
+// ignored. It returns how many tags were added across all transactions.
+func (s *TagsStorage) Tag(ctx context.Context, userID uint, q TransactionQuery, names []string) (int64, error) {
+	var added int64
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tags, err := ensureTags(tx, userID, names)
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			result := tx.Exec(`
+				INSERT INTO transaction_tags (transaction_id, tag_id)
+				SELECT id, ? FROM (?) matched
+				ON CONFLICT DO NOTHING`, tag.ID, matchingTransactions(tx, userID, q))
+			if result.Error != nil {
+				return result.Error
+			}
+			added += result.RowsAffected
+		}
+
+		return nil
+	})
+
+	return added, err
+}
+
+// Untag removes the named tags from every transaction matching the filters
+// in q. Names the user has no tag for are ignored. It returns how many tags
+// were removed across all transactions.
+func (s *TagsStorage) Untag(ctx context.Context, userID uint, q TransactionQuery, names []string) (int64, error) {
+	db := s.db.WithContext(ctx)
+
+	var tagIDs []uint
+	err := db.Model(&model.Tag{}).
+		Where("user_id = ? AND name IN ?", userID, names).
+		Pluck("id", &tagIDs).Error
+	if err != nil || len(tagIDs) == 0 {
+		return 0, err
+	}
+
+	result := db.Exec(`
+		DELETE FROM transaction_tags
+		WHERE tag_id IN ? AND transaction_id IN (?)`, tagIDs, matchingTransactions(db, userID, q))
+
+	return result.RowsAffected, result.Error
+}
+
+// matchingTransactions selects the ids of the user's transactions that match
+// the filters in q, for use as a subquery.
+func matchingTransactions(db *gorm.DB, userID uint, q TransactionQuery) *gorm.DB {
+	query := db.Session(&gorm.Session{NewDB: true}).
+		Model(&model.Transaction{}).
+		Select("id").
+		Where("user_id = ?", userID)
+
+	return applyTransactionFilters(query, q)
+}
+
+// checkTagName fails with ErrConflict when the user already has another tag
+// with the same name, ignoring case.
+func checkTagName(tx *gorm.DB, tag *model.Tag) error {
+	var count int64
+	err := tx.Model(&model.Tag{}).
+		Where("user_id = ? AND name = ? AND id <> ?", tag.UserID, tag.Name, tag.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// ensureTags returns the user's tags with the given names, creating any that
+// do not exist yet. Names are matched case-insensitively and the result is
+// keyed by the lowercased name.
+func ensureTags(tx *gorm.DB, userID uint, names []string) (map[string]model.Tag, error) {
+	tags := make(map[string]model.Tag, len(names))
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	var existing []model.Tag
+	err := tx.Where("user_id = ? AND name IN ?", userID, names).Find(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range existing {
+		tags[strings.ToLower(t.Name)] = t
+	}
+
+	for _, n := range names {
+		key := strings.ToLower(n)
+		if _, ok := tags[key]; ok {
+			continue
+		}
+
+		tag := model.Tag{UserID: userID, Name: n}
+		if err := tx.Create(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags[key] = tag
+	}
+
+	return tags, nil
+}
+
+// attachTags adds the named tags to each transaction in byTransaction. Tags a
+// transaction already has are left alone.
+func attachTags(tx *gorm.DB, userID uint, byTransaction map[uint][]string) error {
+	var names []string
+	seen := map[string]bool{}
+	for _, tagNames := range byTransaction {
+		for _, n := range tagNames {
+			if key := strings.ToLower(n); !seen[key] {
+				seen[key] = true
+				names = append(names, n)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags, err := ensureTags(tx, userID, names)
+	if err != nil {
+		return err
+	}
+
+	type transactionTag struct {
+		TransactionID uint
+		TagID         uint
+	}
+
+	var rows []transactionTag
+	for txnID, tagNames := range byTransaction {
+		for _, n := range tagNames {
+			rows = append(rows, transactionTag{TransactionID: txnID, TagID: tags[strings.ToLower(n)].ID})
+		}
+	}
+
+	return tx.Table("transaction_tags").
+		Clauses(onConflictDoNothing).
+		CreateInBatches(rows, importInsertBatchSize).Error
+}
@@ -0,0 +1,340 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+// minSplitLines is the fewest lines a split transaction can have.
+const minSplitLines = 2
+
+var ErrInvalidSplit = errors.New("invalid split")
+
+type TransactionsStorage struct {
+	db *gorm.DB
+}
+
+// Create inserts a manually entered transaction after running the user's
+// rules over it. A category chosen by the user is never replaced by a rule.
+// When txn has splits it is stored as a split transaction and rules do not
+// categorise it.
+func (s *TransactionsStorage) Create(ctx context.Context, txn *model.Transaction) error {
+	if len(txn.Splits) > 0 && txn.CategoryID != nil {
+		return ErrInvalidSplit
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTransactionCategory(tx, txn.UserID, txn.CategoryID); err != nil {
+			return err
+		}
+
+		engine, err := loadRuleEngine(tx, txn.UserID)
+		if err != nil {
+			return err
+		}
+		out := engine.Apply(txn, false)
+
+		if len(txn.Splits) > 0 {
+			txn.IsSplit = true
+			txn.CategoryID = nil
+			if err := checkSplits(tx, txn); err != nil {
+				return err
+			}
+		}
+
+		if err := adjustBalance(tx, txn.UserID, txn.AccountID, txn.Amount); err != nil {
+			return err
+		}
+
+		if err := tx.Create(txn).Error; err != nil {
+			return err
+		}
+
+		return attachTags(tx, txn.UserID, map[uint][]string{txn.ID: out.AddTags})
+	})
+}
+
+func (s *TransactionsStorage) GetByID(ctx context.Context, userID, transactionID uint) (*model.Transaction, error) {
+	var txn model.Transaction
+	err := s.db.WithContext(ctx).
+		Preload("Splits", orderSplits).
+		Preload("Tags").
+		Where("id = ? AND user_id = ?", transactionID, userID).
+		First(&txn).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &txn, nil
+}
+
+// List returns one page of transactions matching q together with the cursor
+// for the next page, which is empty once the last page has been reached.
+func (s *TransactionsStorage) List(ctx context.Context, userID uint, q TransactionQuery) ([]model.Transaction, string, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+
+	if q.Cursor != "" {
+		cursor, err := decodeTransactionCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("(date, id) < (?, ?)", cursor.Date, cursor.ID)
+	}
+
+	query = applyTransactionFilters(query, q)
+
+	transactions := []model.Transaction{}
+	err := query.
+		Preload("Splits", orderSplits).
+		Preload("Tags").
+		Order("date DESC, id DESC").
+		Limit(q.Limit + 1).
+		Find(&transactions).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(transactions) > q.Limit {
+		transactions = transactions[:q.Limit]
+		last := transactions[len(transactions)-1]
+		next = transactionCursor{Date: last.Date, ID: last.ID}.encode()
+	}
+
+	return transactions, next, nil
+}
+
+// Update writes txn over the stored row and moves the difference between the
+// old and new amounts onto the affected account balances. When txn is one leg
+// of a transfer its counterpart is updated to match. A split transaction
+// keeps its lines, so its amount can only change together with them through
+// SetSplits.
+func (s *TransactionsStorage) Update(ctx context.Context, txn *model.Transaction) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Transaction
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", txn.ID, txn.UserID).
+			First(&current).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if err := adjustBalance(tx, current.UserID, current.AccountID, -current.Amount); err != nil {
+			return err
+		}
+		if err := adjustBalance(tx, txn.UserID, txn.AccountID, txn.Amount); err != nil {
+			return err
+		}
+
+		if current.TransferPeerID != nil {
+			txn.IsTransfer = true
+			txn.TransferPeerID = current.TransferPeerID
+			txn.CategoryID = nil
+			if err := syncTransferPeer(tx, txn); err != nil {
+				return err
+			}
+		} else if err := checkTransactionCategory(tx, txn.UserID, txn.CategoryID); err != nil {
+			return err
+		}
+
+		if current.IsSplit && (txn.Amount != current.Amount || txn.CategoryID != nil) {
+			return ErrInvalidSplit
+		}
+
+		return tx.Model(&current).
+			Select("account_id", "category_id", "amount", "date", "payee", "memo").
+			Updates(txn).Error
+	})
+}
+
+// Delete removes a transaction, and its counterpart when it is a transfer leg.
+// Its split lines and the attachment records of both go with it; it returns
+// the keys of the attachment blobs, which are up to the caller to remove.
+func (s *TransactionsStorage) Delete(ctx context.Context, userID, transactionID uint) ([]string, error) {
+	var keys []string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Transaction
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", transactionID, userID).
+			First(&current).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if err := adjustBalance(tx, current.UserID, current.AccountID, -current.Amount); err != nil {
+			return err
+		}
+
+		deleted := []uint{current.ID}
+		if current.TransferPeerID != nil {
+			if err := deleteTransferPeer(tx, &current); err != nil {
+				return err
+			}
+			deleted = append(deleted, *current.TransferPeerID)
+		}
+
+		if err := tx.Where("transaction_id = ?", current.ID).Delete(&model.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		if keys, err = deleteAttachments(tx, current.UserID, deleted); err != nil {
+			return err
+		}
+
+		return tx.Delete(&current).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// SetSplits replaces the lines of a transaction. Lines must add up to the
+// transaction amount; an empty list turns a split transaction back into an
+// uncategorised one. Transfers cannot be split.
+func (s *TransactionsStorage) SetSplits(ctx context.Context, userID, transactionID uint, splits []model.TransactionSplit) (*model.Transaction, error) {
+	var txn model.Transaction
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(lockForUpdate).
+			Where("id = ? AND user_id = ?", transactionID, userID).
+			First(&txn).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		txn.Splits = splits
+		if len(splits) > 0 {
+			if err := checkSplits(tx, &txn); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("transaction_id = ?", txn.ID).Delete(&model.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+
+		txn.IsSplit = len(splits) > 0
+		txn.CategoryID = nil
+		err = tx.Model(&txn).
+			Select("is_split", "category_id").
+			Updates(&txn).Error
+		if err != nil {
+			return err
+		}
+
+		if len(splits) == 0 {
+			txn.Splits = nil
+			return nil
+		}
+		for i := range txn.Splits {
+			txn.Splits[i].ID = 0
+			txn.Splits[i].TransactionID = txn.ID
+		}
+		return tx.Create(&txn.Splits).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &txn, nil
+}
+
+// checkSplits makes sure the lines of txn are valid: at least two non-zero
+// lines in the user's own categories, adding up to the transaction amount.
+func checkSplits(tx *gorm.DB, txn *model.Transaction) error {
+	if txn.IsTransfer || txn.TransferPeerID != nil || len(txn.Splits) < minSplitLines {
+		return ErrInvalidSplit
+	}
+
+	var total int64
+	for _, line := range txn.Splits {
+		if line.Amount == 0 {
+			return ErrInvalidSplit
+		}
+		total += line.Amount
+
+		if err := checkTransactionCategory(tx, txn.UserID, line.CategoryID); err != nil {
+			return err
+		}
+	}
+	if total != txn.Amount {
+		return ErrInvalidSplit
+	}
+
+	return nil
+}
+
+func orderSplits(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+func applyTransactionFilters(query *gorm.DB, q TransactionQuery) *gorm.DB {
+	if q.AccountID != nil {
+		query = query.Where("account_id = ?", *q.AccountID)
+	}
+	if q.CategoryID != nil {
+		query = query.Where(
+			"(category_id = ? OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = ?))",
+			*q.CategoryID, *q.CategoryID)
+	}
+	if q.TagID != nil {
+		query = query.Where("id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id = ?)", *q.TagID)
+	}
+	if q.From != nil {
+		query = query.Where("date >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("date <= ?", *q.To)
+	}
+	if q.MinAmount != nil {
+		query = query.Where("amount >= ?", *q.MinAmount)
+	}
+	if q.MaxAmount != nil {
+		query = query.Where("amount <= ?", *q.MaxAmount)
+	}
+	if q.Search != "" {
+		query = query.Where("payee ILIKE ?", "%"+escapeLike(q.Search)+"%")
+	}
+
+	return query
+}
+
+// adjustBalance adds delta to the balance of the given account, failing with
+// ErrNotFound when the account does not exist or belongs to someone else.
+func adjustBalance(tx *gorm.DB, userID, accountID uint, delta int64) error {
+	result := tx.Model(&model.Account{}).
+		Where("id = ? AND user_id = ?", accountID, userID).
+		Update("balance", gorm.Expr("balance + ?", delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
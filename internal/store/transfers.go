@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+// Transfer describes money moved between two accounts owned by the same user.
+type Transfer struct {
+	UserID        uint
+	FromAccountID uint
+	ToAccountID   uint
+	Amount        int64
+	Date          time.Time
+	Memo          string
+}
+
+// CreateTransfer writes the debit and credit legs of t and links them to each
+// other in a single database transaction. Both accounts must belong to
+// t.UserID and share a currency.
+func (s *TransactionsStorage) CreateTransfer(ctx context.Context, t Transfer) (*model.Transaction, *model.Transaction, error) {
+	if t.FromAccountID == t.ToAccountID || t.Amount <= 0 {
+		return nil, nil, ErrInvalidTransfer
+	}
+
+	var debit, credit model.Transaction
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var accounts []model.Account
+		err := tx.Clauses(lockForUpdate).
+			Where("id IN ? AND user_id = ?", []uint{t.FromAccountID, t.ToAccountID}, t.UserID).
+			Order("id").
+			Find(&accounts).Error
+		if err != nil {
+			return err
+		}
+		if len(accounts) != 2 {
+			return ErrNotFound
+		}
+		if accounts[0].Currency != accounts[1].Currency {
+			return ErrInvalidTransfer
+		}
+
+		from, to := accounts[0], accounts[1]
+		if from.ID != t.FromAccountID {
+			from, to = to, from
+		}
+
+		debit = model.Transaction{
+			UserID:     t.UserID,
+			AccountID:  from.ID,
+			Amount:     -t.Amount,
+			Date:       t.Date,
+			Payee:      "Transfer to " + to.Name,
+			Memo:       t.Memo,
+			IsTransfer: true,
+		}
+		credit = model.Transaction{
+			UserID:     t.UserID,
+			AccountID:  to.ID,
+			Amount:     t.Amount,
+			Date:       t.Date,
+			Payee:      "Transfer from " + from.Name,
+			Memo:       t.Memo,
+			IsTransfer: true,
+		}
+
+		if err := tx.Create(&debit).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&credit).Error; err != nil {
+			return err
+		}
+
+		debit.TransferPeerID = &credit.ID
+		credit.TransferPeerID = &debit.ID
+
+		if err := tx.Model(&debit).Update("transfer_peer_id", credit.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&credit).Update("transfer_peer_id", debit.ID).Error; err != nil {
+			return err
+		}
+
+		if err := adjustBalance(tx, t.UserID, from.ID, -t.Amount); err != nil {
+			return err
+		}
+		return adjustBalance(tx, t.UserID, to.ID, t.Amount)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &debit, &credit, nil
+}
+
+// syncTransferPeer mirrors an edit made to one leg of a transfer onto the
+// other leg so the two never drift apart. Like CreateTransfer, it refuses
+// legs on accounts in different currencies, since the peer gets the same
+// amount.
+func syncTransferPeer(tx *gorm.DB, leg *model.Transaction) error {
+	var peer model.Transaction
+	err := tx.Clauses(lockForUpdate).
+		Where("id = ? AND user_id = ?", *leg.TransferPeerID, leg.UserID).
+		First(&peer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if peer.AccountID == leg.AccountID {
+		return ErrInvalidTransfer
+	}
+
+	var currencies []string
+	err = tx.Model(&model.Account{}).
+		Where("id IN ? AND user_id = ?", []uint{leg.AccountID, peer.AccountID}, leg.UserID).
+		Distinct().
+		Pluck("currency", &currencies).Error
+	if err != nil {
+		return err
+	}
+	if len(currencies) != 1 {
+		return ErrInvalidTransfer
+	}
+
+	if err := adjustBalance(tx, peer.UserID, peer.AccountID, -leg.Amount-peer.Amount); err != nil {
+		return err
+	}
+
+	return tx.Model(&peer).Updates(map[string]any{
+		"amount": -leg.Amount,
+		"date":   leg.Date,
+		"memo":   leg.Memo,
+	}).Error
+}
+
+// deleteTransferPeer removes the counterpart of a transfer leg that is being
+// deleted and reverses its effect on the balance.
+func deleteTransferPeer(tx *gorm.DB, leg *model.Transaction) error {
+	var peer model.Transaction
+	err := tx.Clauses(lockForUpdate).
+		Where("id = ? AND user_id = ?", *leg.TransferPeerID, leg.UserID).
+		First(&peer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := adjustBalance(tx, peer.UserID, peer.AccountID, -peer.Amount); err != nil {
+		return err
+	}
+
+	return tx.Delete(&peer).Error
+}
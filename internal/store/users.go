@@ -2,26 +2,217 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/nelsonfrank/finance-tracker/internal/db/model"
 	"gorm.io/gorm"
 )
 
-type User struct {
-	gorm.Model
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	CreatedAt time.Time `gorm:"type:timestamp with time zone;not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time `gorm:"type:timestamp with time zone;not null;default:CURRENT_TIMESTAMP"`
-}
-
 type UsersStorage struct {
 	db *gorm.DB
 }
 
-func (s *UsersStorage) Create(ctx context.Context, user *User) error {
+// Create inserts a new user and seeds their default category tree in the same
+// database transaction, so a user never exists without categories.
+func (s *UsersStorage) Create(ctx context.Context, user *model.User) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return seedDefaultCategories(tx, user.ID)
+	})
+}
+
+// CreateAndInvite creates a user, as Create does, together with the
+// invitation whose token hash is tokenHash and which expires after exp.
+func (s *UsersStorage) CreateAndInvite(ctx context.Context, user *model.User, tokenHash string, exp time.Duration) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		if err := seedDefaultCategories(tx, user.ID); err != nil {
+			return err
+		}
+
+		return createInvitation(tx, user.ID, tokenHash, exp)
+	})
+}
+
+// Invite replaces any outstanding invitation of the user with a new one.
+func (s *UsersStorage) Invite(ctx context.Context, userID uint, tokenHash string, exp time.Duration) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserInvitation{}).Error; err != nil {
+			return err
+		}
+
+		return createInvitation(tx, userID, tokenHash, exp)
+	})
+}
+
+// LastInvitedAt returns when the user's outstanding invitation was sent, or
+// the zero time when there is none.
+func (s *UsersStorage) LastInvitedAt(ctx context.Context, userID uint) (time.Time, error) {
+	var invitation model.UserInvitation
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return invitation.CreatedAt, nil
+}
+
+// Activate marks the user holding the invitation with the given token hash
+// as active and uses the invitation up. Unknown and expired tokens fail with
+// ErrNotFound.
+func (s *UsersStorage) Activate(ctx context.Context, tokenHash string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invitation model.UserInvitation
+		err := tx.Clauses(lockForUpdate).
+			Where("token_hash = ? AND expires_at > ?", tokenHash, now).
+			First(&invitation).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		result := tx.Model(&model.User{}).
+			Where("id = ?", invitation.UserID).
+			Update("is_active", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return tx.Where("user_id = ?", invitation.UserID).Delete(&model.UserInvitation{}).Error
+	})
+}
+
+// Delete permanently removes a user who never got to use their account,
+// such as one whose invitation email could not be sent, along with the
+// categories and invitation created for them.
+func (s *UsersStorage) Delete(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.Category{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&model.User{}, userID).Error
+	})
+}
+
+// RequestPasswordReset replaces any outstanding password reset of the user
+// with a new one.
+func (s *UsersStorage) RequestPasswordReset(ctx context.Context, userID uint, tokenHash string, exp time.Duration) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.PasswordReset{}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Create(&model.PasswordReset{
+			UserID:    userID,
+			TokenHash: tokenHash,
+			ExpiresAt: now.Add(exp),
+			CreatedAt: now,
+		}).Error
+	})
+}
+
+// LastPasswordResetAt returns when the user's outstanding password reset was
+// requested, or the zero time when there is none.
+func (s *UsersStorage) LastPasswordResetAt(ctx context.Context, userID uint) (time.Time, error) {
+	var reset model.PasswordReset
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&reset).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return reset.CreatedAt, nil
+}
+
+// ResetPassword sets passwordHash as the password of the user holding the
+// reset with the given token hash and uses the reset up. Every refresh token
+// of the user is revoked. Since the token was received by email it also
+// proves the user owns the address, so the account is activated as well.
+// Unknown and expired tokens fail with ErrNotFound.
+func (s *UsersStorage) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reset model.PasswordReset
+		err := tx.Clauses(lockForUpdate).
+			Where("token_hash = ? AND expires_at > ?", tokenHash, now).
+			First(&reset).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		result := tx.Model(&model.User{}).
+			Where("id = ?", reset.UserID).
+			Updates(map[string]any{
+				"password":  passwordHash,
+				"is_active": true,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if err := revokeSessions(tx, now, "user_id = ?", reset.UserID); err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", reset.UserID).Delete(&model.PasswordReset{}).Error
+	})
+}
+
+func createInvitation(tx *gorm.DB, userID uint, tokenHash string, exp time.Duration) error {
+	now := time.Now()
+
+	return tx.Create(&model.UserInvitation{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(exp),
+		CreatedAt: now,
+	}).Error
+}
+
+func (s *UsersStorage) SetHomeCurrency(ctx context.Context, userID uint, currency string) error {
+	result := s.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("home_currency", currency)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
 
 	return nil
 }